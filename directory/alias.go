@@ -0,0 +1,46 @@
+package directory
+
+import (
+	"fmt"
+	"sync"
+)
+
+// userAliases maps field names used by some directory tenants to the JSON name of the User field they stand for.
+var userAliases = struct {
+	sync.RWMutex
+	names map[string]string
+}{names: make(map[string]string)}
+
+// RegisterUserFieldAlias makes User decode the JSON key alias into the User field with the JSON name field, for
+// tenants that name the field differently, such as "employeeId" for "id". When a payload carries both names the
+// field name wins. Like field names, an alias matches in both its camelCase and snake_case form, so "staff_number"
+// also decodes "staffNumber". Aliases apply to every client in the process.
+func RegisterUserFieldAlias(alias, field string) error {
+	alias = camelCase(alias)
+	if !userFields[field] {
+		return fmt.Errorf("unknown User field %q", field)
+	}
+	if userFields[alias] {
+		return fmt.Errorf("alias %q is already a User field", alias)
+	}
+
+	userAliases.Lock()
+	defer userAliases.Unlock()
+	userAliases.names[alias] = field
+
+	return nil
+}
+
+// userFieldName returns the JSON name of the User field that the key name, in camelCase or snake_case form or as
+// a registered alias, decodes into.
+func userFieldName(name string) string {
+	name = camelCase(name)
+
+	userAliases.RLock()
+	defer userAliases.RUnlock()
+	if field, ok := userAliases.names[name]; ok {
+		return field
+	}
+
+	return name
+}
@@ -0,0 +1,64 @@
+package directory
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRegisterUserFieldAlias(t *testing.T) {
+	if err := RegisterUserFieldAlias("employeeId", "id"); err != nil {
+		t.Fatalf("RegisterUserFieldAlias() returned error: %v", err)
+	}
+	defer func() {
+		userAliases.Lock()
+		delete(userAliases.names, "employeeId")
+		userAliases.Unlock()
+	}()
+
+	u := new(User)
+	if err := json.Unmarshal([]byte(`{"employeeId":"erick","fullName":"Erick Guevara"}`), u); err != nil {
+		t.Fatalf("Unmarshal() returned error: %v", err)
+	}
+	if expected := (&User{ID: "erick", FullName: "Erick Guevara"}); !reflect.DeepEqual(u, expected) {
+		t.Errorf("Unmarshal() = %+v, expected %+v", u, expected)
+	}
+
+	u = new(User)
+	json.Unmarshal([]byte(`{"employee_id":"alias","id":"erick"}`), u)
+	if u.ID != "erick" {
+		t.Errorf("Unmarshal() ID = %q, expected the id field to win over its alias", u.ID)
+	}
+
+	if err := checkUnknownFields([]byte(`{"employeeId":"erick"}`), reflect.TypeOf(u)); err != nil {
+		t.Errorf("checkUnknownFields() rejected a registered alias: %v", err)
+	}
+
+	if err := RegisterUserFieldAlias("staffId", "nope"); err == nil {
+		t.Errorf("RegisterUserFieldAlias() expected error for an unknown field")
+	}
+	if err := RegisterUserFieldAlias("fullName", "id"); err == nil {
+		t.Errorf("RegisterUserFieldAlias() expected error for an alias shadowing a field")
+	}
+	if err := RegisterUserFieldAlias("full_name", "id"); err == nil {
+		t.Errorf("RegisterUserFieldAlias() expected error for a snake_case alias shadowing a field")
+	}
+}
+
+func TestRegisterUserFieldAlias_snakeCase(t *testing.T) {
+	if err := RegisterUserFieldAlias("staff_number", "id"); err != nil {
+		t.Fatalf("RegisterUserFieldAlias() returned error: %v", err)
+	}
+	defer func() {
+		userAliases.Lock()
+		delete(userAliases.names, "staffNumber")
+		userAliases.Unlock()
+	}()
+
+	for _, payload := range []string{`{"staff_number":"erick"}`, `{"staffNumber":"erick"}`} {
+		u := new(User)
+		if err := json.Unmarshal([]byte(payload), u); err != nil || u.ID != "erick" {
+			t.Errorf("Unmarshal(%s) = %+v, %v, expected ID erick", payload, u, err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package directory
+
+import "time"
+
+// Operations reported in AuditEntry.Operation.
+const (
+	AuditCreate       = "Users.Create"
+	AuditStreamCreate = "Users.StreamCreate"
+)
+
+// AuditEntry records one mutation performed through the client.
+type AuditEntry struct {
+	// Operation is the mutating method, such as AuditCreate.
+	Operation string
+
+	// ID of the employee the mutation applied to.
+	ID string
+
+	// Payload is the value sent to the directory, nil when it was streamed and not retained.
+	Payload interface{}
+
+	// After is the state of the employee as stored by the directory, nil when the response did not include it.
+	After *User
+
+	// Time the mutation completed, read from the client clock.
+	Time time.Time
+}
+
+// SetAuditSink is a client option for receiving an AuditEntry for every mutation the directory accepted, for
+// compliance logging. Reads, mutations that failed and mutations captured in dry-run mode, which never reach the
+// directory, are not reported. sink is called synchronously by the mutating method before it returns, so it should
+// be quick or hand the entry off.
+func SetAuditSink(sink func(AuditEntry)) ClientOpt {
+	return func(c *Client) error {
+		c.auditSink = sink
+		return nil
+	}
+}
+
+// audit sends e to the audit sink of c, if any. Nothing is sent in dry-run mode.
+func (c *Client) audit(e AuditEntry) {
+	if c.auditSink == nil || c.dryRun != nil {
+		return
+	}
+
+	e.Time = c.clock.Now()
+	c.auditSink(e)
+}
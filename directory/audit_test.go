@@ -0,0 +1,96 @@
+package directory
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestSetAuditSink(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, `{"employees":[{"id":"erick"}]}`)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"erick","coreId":"aeg095"}`)
+	})
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"erick"}`)
+	})
+
+	clock := newFakeClock()
+	SetClock(clock)(client)
+
+	var entries []AuditEntry
+	SetAuditSink(func(e AuditEntry) {
+		entries = append(entries, e)
+	})(client)
+
+	// Reads are not audited.
+	client.Users.Get(ctx, "erick", nil)
+	client.Users.List(ctx, nil)
+	if len(entries) != 0 {
+		t.Fatalf("reads were audited: %+v", entries)
+	}
+
+	input := &User{ID: "erick", FullName: "Erick Guevara"}
+	if _, _, err := client.Users.Create(ctx, input); err != nil {
+		t.Fatalf("Users.Create() returned error: %v", err)
+	}
+
+	expected := []AuditEntry{{
+		Operation: AuditCreate,
+		ID:        "erick",
+		Payload:   input,
+		After:     &User{ID: "erick", CoreID: "aeg095"},
+		Time:      clock.Now(),
+	}}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("audit sink received %+v, expected %+v", entries, expected)
+	}
+}
+
+func TestSetAuditSink_dryRun(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var entries []AuditEntry
+	SetAuditSink(func(e AuditEntry) {
+		entries = append(entries, e)
+	})(client)
+	SetDryRun(true)(client)
+
+	if _, _, err := client.Users.Create(ctx, &User{ID: "erick"}); err != nil {
+		t.Fatalf("Users.Create() returned error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("dry-run Create was audited: %+v", entries)
+	}
+}
+
+func TestSetAuditSink_failedMutation(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, employeeDoesNotExist)
+	})
+
+	var entries []AuditEntry
+	SetAuditSink(func(e AuditEntry) {
+		entries = append(entries, e)
+	})(client)
+
+	if _, _, err := client.Users.Create(ctx, &User{ID: "erick"}); err == nil {
+		t.Fatalf("Users.Create() expected error")
+	}
+	if len(entries) != 0 {
+		t.Errorf("failed create was audited: %+v", entries)
+	}
+}
@@ -0,0 +1,100 @@
+package directory
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// TokenSource returns a bearer token for authenticating requests.
+type TokenSource func(ctx context.Context) (string, error)
+
+// tokenCache keeps the last token of a TokenSource until the server rejects it.
+type tokenCache struct {
+	source TokenSource
+
+	mu    sync.Mutex
+	token string
+}
+
+// SetTokenSource is a client option for authenticating every request with a bearer token obtained from source,
+// such as a short-lived OAuth token, instead of the fixed token of SetToken. The token is reused until a request
+// fails with 401 Unauthorized; Do then asks source for a fresh token and retries the request once.
+func SetTokenSource(source TokenSource) ClientOpt {
+	return func(c *Client) error {
+		c.tokens = &tokenCache{source: source}
+		return nil
+	}
+}
+
+// get returns the cached token, asking the source for one when there is none.
+func (t *tokenCache) get(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" {
+		return t.token, nil
+	}
+
+	token, err := t.source(ctx)
+	if err != nil {
+		return "", err
+	}
+	t.token = token
+
+	return token, nil
+}
+
+// invalidate drops token from the cache, unless another request already replaced it.
+func (t *tokenCache) invalidate(token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token == token {
+		t.token = ""
+	}
+}
+
+// authorizedRoundTrip sends req with a token from the client token source, if any, refreshing the token and
+// retrying once when the server answers 401 Unauthorized. Every request sent is counted in attempts.
+func (c *Client) authorizedRoundTrip(ctx context.Context, req *http.Request, ro *requestOptions, attempts *int) (*http.Response, error) {
+	if c.tokens == nil {
+		return c.roundTrip(ctx, req, ro, attempts)
+	}
+
+	token, err := c.tokens.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The header is set on a copy, so the caller's request is left as built by NewRequest.
+	req = req.Clone(ctx)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.roundTrip(ctx, req, ro, attempts)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+
+	c.tokens.invalidate(token)
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if token, err = c.tokens.get(ctx); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+	}
+
+	return c.roundTrip(ctx, req, ro, attempts)
+}
@@ -0,0 +1,70 @@
+package directory
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestSetTokenSource(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var issued []string
+	SetTokenSource(func(context.Context) (string, error) {
+		token := fmt.Sprintf("token-%d", len(issued)+1)
+		issued = append(issued, token)
+		return token, nil
+	})(client)
+
+	valid := "token-1"
+	var bodies []string
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if r.Header.Get("Authorization") != "Bearer "+valid {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+
+	// The token is fetched once and reused.
+	for i := 0; i < 2; i++ {
+		req, _ := client.NewRequest("GET", "employee", nil)
+		if _, err := client.Do(ctx, req, nil); err != nil {
+			t.Fatalf("Do() returned error: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(issued, []string{"token-1"}) {
+		t.Errorf("token source issued %v, expected a single token", issued)
+	}
+
+	// Once the token expires the request is retried with a fresh one.
+	valid = "token-2"
+	bodies = nil
+	req, _ := client.NewRequest("POST", "employee", &User{ID: "erick"})
+	if _, err := client.Do(ctx, req, nil); err != nil {
+		t.Fatalf("Do() after the token expired returned error: %v", err)
+	}
+	if !reflect.DeepEqual(issued, []string{"token-1", "token-2"}) {
+		t.Errorf("token source issued %v, expected a refresh", issued)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] {
+		t.Errorf("server received %q, expected the body resent on the retry", bodies)
+	}
+	if got := req.Header.Get("Authorization"); got != "" {
+		t.Errorf("Do() set Authorization %q on the caller's request", got)
+	}
+
+	// A request still rejected after the refresh fails.
+	valid = "none"
+	req, _ = client.NewRequest("GET", "employee", nil)
+	if _, err := client.Do(ctx, req, nil); err == nil {
+		t.Errorf("Do() expected error when the fresh token is rejected too")
+	}
+	if len(issued) != 3 {
+		t.Errorf("token source issued %v, expected a single refresh per request", issued)
+	}
+}
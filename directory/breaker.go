@@ -0,0 +1,110 @@
+package directory
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by Do without sending the request while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+// Circuit breaker states.
+const (
+	// BreakerClosed lets every request through.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen fails every request fast with ErrCircuitOpen.
+	BreakerOpen
+
+	// BreakerHalfOpen lets a single probe request through to test the backend.
+	BreakerHalfOpen
+)
+
+// CircuitBreaker stops a Client from hammering a failing directory backend. After Threshold consecutive failures
+// the breaker opens and requests fail fast with ErrCircuitOpen. Once Cooldown has elapsed a single probe request is
+// let through: a success closes the breaker again, a failure re-opens it for another cooldown.
+type CircuitBreaker struct {
+	// Number of consecutive failures that opens the breaker.
+	Threshold int
+
+	// Time the breaker stays open before a probe request is allowed.
+	Cooldown time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{Threshold: threshold, Cooldown: cooldown}
+}
+
+// SetCircuitBreaker is a client option for guarding every request with the given circuit breaker.
+func SetCircuitBreaker(b *CircuitBreaker) ClientOpt {
+	return func(c *Client) error {
+		c.breaker = b
+		return nil
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// allow reports whether a request may be sent at now, moving an open breaker to half-open once the cooldown is over.
+func (b *CircuitBreaker) allow(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.Cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		return nil
+	case BreakerHalfOpen:
+		// A probe is already in flight.
+		return ErrCircuitOpen
+	}
+
+	return nil
+}
+
+// record updates the breaker with the outcome, known at now, of a request let through by allow.
+func (b *CircuitBreaker) record(success bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.Threshold {
+		b.state = BreakerOpen
+		b.openedAt = now
+	}
+}
+
+// release gives up a probe whose outcome is unknown, so that the next request can probe again.
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen {
+		b.state = BreakerOpen
+	}
+}
@@ -0,0 +1,106 @@
+package directory
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func testBreakerState(t *testing.T, b *CircuitBreaker, expected BreakerState) {
+	if got := b.State(); got != expected {
+		t.Errorf("CircuitBreaker.State() = %v, expected %v", got, expected)
+	}
+}
+
+func TestCircuitBreaker_transitions(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewCircuitBreaker(2, time.Minute)
+
+	b.record(false, now)
+	testBreakerState(t, b, BreakerClosed)
+
+	b.record(false, now)
+	testBreakerState(t, b, BreakerOpen)
+	if err := b.allow(now.Add(time.Minute - 1)); err != ErrCircuitOpen {
+		t.Errorf("allow() while open = %v, expected %v", err, ErrCircuitOpen)
+	}
+
+	now = now.Add(time.Minute)
+	if err := b.allow(now); err != nil {
+		t.Errorf("allow() after cooldown = %v, expected probe to be allowed", err)
+	}
+	testBreakerState(t, b, BreakerHalfOpen)
+	if err := b.allow(now); err != ErrCircuitOpen {
+		t.Errorf("allow() during probe = %v, expected %v", err, ErrCircuitOpen)
+	}
+
+	// A failed probe re-opens the breaker for another cooldown.
+	b.record(false, now)
+	testBreakerState(t, b, BreakerOpen)
+
+	now = now.Add(time.Minute)
+	if err := b.allow(now); err != nil {
+		t.Errorf("allow() after cooldown = %v, expected probe to be allowed", err)
+	}
+	b.record(true, now)
+	testBreakerState(t, b, BreakerClosed)
+
+	// A probe whose outcome is unknown lets the next request probe again.
+	b.record(false, now)
+	b.record(false, now)
+	now = now.Add(time.Minute)
+	b.allow(now)
+	b.release()
+	if err := b.allow(now); err != nil {
+		t.Errorf("allow() after released probe = %v, expected probe to be allowed", err)
+	}
+	b.record(true, now)
+
+	// The failure count starts over once closed.
+	b.record(false, now)
+	testBreakerState(t, b, BreakerClosed)
+}
+
+func TestDo_circuitBreaker(t *testing.T) {
+	setup()
+	defer teardown()
+
+	status, hits := http.StatusInternalServerError, 0
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(status)
+	})
+
+	clock := newFakeClock()
+	b := NewCircuitBreaker(2, time.Minute)
+	SetClock(clock)(client)
+	if err := SetCircuitBreaker(b)(client); err != nil {
+		t.Fatalf("SetCircuitBreaker() unexpected error: %v", err)
+	}
+
+	do := func() error {
+		req, _ := client.NewRequest("GET", "/", nil)
+		_, err := client.Do(ctx, req, nil)
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := do(); err == nil {
+			t.Fatalf("Do() expected server error")
+		}
+	}
+
+	if err := do(); err != ErrCircuitOpen {
+		t.Errorf("Do() with open breaker = %v, expected %v", err, ErrCircuitOpen)
+	}
+	if hits != 2 {
+		t.Errorf("Do() with open breaker reached the server, hits = %d", hits)
+	}
+
+	status = http.StatusOK
+	clock.Advance(time.Minute)
+	if err := do(); err != nil {
+		t.Errorf("Do() probe unexpected error: %v", err)
+	}
+	testBreakerState(t, b, BreakerClosed)
+}
@@ -0,0 +1,66 @@
+package directory
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClientCancelled is returned by Do after CancelAll, until Reset is called.
+var ErrClientCancelled = errors.New("client requests were cancelled")
+
+// cancelGroup is the root every request context of a Client is bound to.
+type cancelGroup struct {
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// root returns the current root context, creating it on first use.
+func (g *cancelGroup) root() context.Context {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.ctx == nil {
+		g.ctx, g.cancel = context.WithCancel(context.Background())
+	}
+
+	return g.ctx
+}
+
+// bind derives a context from ctx that is also cancelled by CancelAll. It fails with ErrClientCancelled when the
+// client has been cancelled. The returned stop function must be called once the request is done.
+func (g *cancelGroup) bind(ctx context.Context) (context.Context, func(), error) {
+	root := g.root()
+	if root.Err() != nil {
+		return nil, nil, ErrClientCancelled
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(root, cancel)
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}, nil
+}
+
+// CancelAll aborts every request of the client in flight and makes later requests fail with ErrClientCancelled,
+// for a graceful shutdown. Reset lets the client send requests again.
+func (c *Client) CancelAll() {
+	c.cancels.root()
+
+	c.cancels.mu.Lock()
+	defer c.cancels.mu.Unlock()
+	c.cancels.cancel()
+}
+
+// Reset undoes CancelAll, so the client sends requests again. Requests cancelled by CancelAll stay cancelled.
+func (c *Client) Reset() {
+	c.cancels.mu.Lock()
+	defer c.cancels.mu.Unlock()
+
+	if c.cancels.ctx != nil && c.cancels.ctx.Err() != nil {
+		c.cancels.ctx, c.cancels.cancel = nil, nil
+	}
+}
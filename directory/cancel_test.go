@@ -0,0 +1,53 @@
+package directory
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_CancelAll(t *testing.T) {
+	setup()
+	defer teardown()
+
+	arrived := make(chan struct{})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	mux.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {})
+
+	done := make(chan error)
+	go func() {
+		req, _ := client.NewRequest("GET", "slow", nil)
+		_, err := client.Do(context.Background(), req, nil)
+		done <- err
+	}()
+
+	<-arrived
+	client.CancelAll()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Do() in flight returned %v, expected %v", err, context.Canceled)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Do() in flight was not cancelled")
+	}
+
+	req, _ := client.NewRequest("GET", "fast", nil)
+	if _, err := client.Do(ctx, req, nil); err != ErrClientCancelled {
+		t.Errorf("Do() after CancelAll returned %v, expected %v", err, ErrClientCancelled)
+	}
+
+	client.Reset()
+	if _, err := client.Do(ctx, req, nil); err != nil {
+		t.Errorf("Do() after Reset returned error: %v", err)
+	}
+}
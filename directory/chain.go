@@ -0,0 +1,76 @@
+package directory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// defaultMaxManagementDepth bounds the management chains resolved by Users.ManagementChain unless
+// SetMaxManagementDepth says otherwise.
+const defaultMaxManagementDepth = 32
+
+var (
+	// ErrManagementCycle is returned by Users.ManagementChain when an employee shows up twice in the chain.
+	ErrManagementCycle = errors.New("management chain has a cycle")
+
+	// ErrManagementChainTooDeep is returned by Users.ManagementChain when the chain has more managers than the
+	// depth set with SetMaxManagementDepth.
+	ErrManagementChainTooDeep = errors.New("management chain is too deep")
+)
+
+// SetMaxManagementDepth is a client option for bounding the number of managers Users.ManagementChain resolves above
+// the employee, so bad data can not keep it fetching forever. The default is 32.
+func SetMaxManagementDepth(n int) ClientOpt {
+	return func(c *Client) error {
+		if n < 1 {
+			return fmt.Errorf("management depth must be at least 1, got %d", n)
+		}
+
+		c.maxManagementDepth = n
+		return nil
+	}
+}
+
+// ManagementChain returns the management chain of the employee mmID: the employee first, then their manager, the
+// manager's manager and so on up to an employee without a manager. Every step is a Users.Get, and the returned
+// Response is that of the last one. ErrManagementCycle is returned when an employee shows up twice and
+// ErrManagementChainTooDeep when the chain outgrows SetMaxManagementDepth; the chain resolved so far is returned
+// with either error.
+func (u *UsersServiceOp) ManagementChain(ctx context.Context, mmID string) ([]*User, *Response, error) {
+	maxDepth := u.client.maxManagementDepth
+	if maxDepth == 0 {
+		maxDepth = defaultMaxManagementDepth
+	}
+
+	var (
+		chain []*User
+		resp  *Response
+	)
+	seen := make(map[string]bool)
+	for id := mmID; ; {
+		if seen[id] {
+			return chain, resp, fmt.Errorf("%w: %v shows up twice", ErrManagementCycle, id)
+		}
+		if len(chain) > maxDepth {
+			return chain, resp, fmt.Errorf("%w: more than %d managers above %v", ErrManagementChainTooDeep, maxDepth,
+				mmID)
+		}
+
+		user, r, err := u.Get(ctx, id, nil)
+		resp = r
+		if err != nil {
+			return chain, resp, err
+		}
+		chain = append(chain, user)
+		seen[id] = true
+		if user.ID != "" {
+			seen[user.ID] = true
+		}
+
+		if user.Manager == nil || user.Manager.ID == "" {
+			return chain, resp, nil
+		}
+		id = user.Manager.ID
+	}
+}
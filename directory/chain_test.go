@@ -0,0 +1,80 @@
+package directory
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestUsers_ManagementChain(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"erick","manager":{"id":"ana"}}`)
+	})
+	mux.HandleFunc("/employee/ana", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"ana","manager":{"id":"ceo"}}`)
+	})
+	mux.HandleFunc("/employee/ceo", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"ceo"}`)
+	})
+
+	chain, _, err := client.Users.ManagementChain(ctx, "erick")
+	if err != nil {
+		t.Fatalf("ManagementChain() returned error: %v", err)
+	}
+
+	var ids []string
+	for _, u := range chain {
+		ids = append(ids, u.ID)
+	}
+	if fmt.Sprint(ids) != "[erick ana ceo]" {
+		t.Errorf("ManagementChain() = %v, expected [erick ana ceo]", ids)
+	}
+}
+
+func TestUsers_ManagementChain_cycle(t *testing.T) {
+	setup()
+	defer teardown()
+
+	hits := 0
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprint(w, `{"id":"erick","manager":{"id":"erick"}}`)
+	})
+
+	chain, _, err := client.Users.ManagementChain(ctx, "erick")
+	if !errors.Is(err, ErrManagementCycle) {
+		t.Errorf("ManagementChain() error = %v, expected %v", err, ErrManagementCycle)
+	}
+	if len(chain) != 1 || hits != 1 {
+		t.Errorf("ManagementChain() returned %d users after %d requests, expected 1 and 1", len(chain), hits)
+	}
+}
+
+func TestUsers_ManagementChain_maxDepth(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/", func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(r.URL.Path, "/employee/%d", &n)
+		fmt.Fprintf(w, `{"id":"%d","manager":{"id":"%d"}}`, n, n+1)
+	})
+
+	SetMaxManagementDepth(2)(client)
+
+	chain, _, err := client.Users.ManagementChain(ctx, "0")
+	if !errors.Is(err, ErrManagementChainTooDeep) {
+		t.Errorf("ManagementChain() error = %v, expected %v", err, ErrManagementChainTooDeep)
+	}
+	if len(chain) != 3 {
+		t.Errorf("ManagementChain() returned %d users, expected the employee and 2 managers", len(chain))
+	}
+
+	if err := SetMaxManagementDepth(0)(client); err == nil {
+		t.Errorf("SetMaxManagementDepth(0) expected error")
+	}
+}
@@ -0,0 +1,35 @@
+package directory
+
+import "time"
+
+// Clock tells the client the current time and how to wait. Time dependent features such as the circuit breaker
+// cooldown and retry backoff go through the client Clock, so tests can control them with SetClock.
+type Clock interface {
+	Now() time.Time
+
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the Clock backed by the system time.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// SetClock is a client option for replacing the clock used by the client, mostly useful in tests.
+func SetClock(clock Clock) ClientOpt {
+	return func(c *Client) error {
+		if clock == nil {
+			clock = realClock{}
+		}
+
+		c.clock = clock
+		return nil
+	}
+}
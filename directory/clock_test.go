@@ -0,0 +1,94 @@
+package directory
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when advanced. After does not block: it records the wait and moves the
+// clock forward by it.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2017, time.June, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+func (f *fakeClock) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.waits = append(f.waits, d)
+	f.now = f.now.Add(d)
+
+	ch := make(chan time.Time, 1)
+	ch <- f.now
+	return ch
+}
+
+func (f *fakeClock) Waits() []time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]time.Duration(nil), f.waits...)
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+}
+
+func TestSetClock(t *testing.T) {
+	c := NewClient()
+	if _, ok := c.clock.(realClock); !ok {
+		t.Errorf("NewClient() clock = %T, expected realClock", c.clock)
+	}
+
+	clock := newFakeClock()
+	SetClock(clock)(c)
+	if c.clock != clock {
+		t.Errorf("SetClock() clock = %T, expected the fake clock", c.clock)
+	}
+
+	SetClock(nil)(c)
+	if _, ok := c.clock.(realClock); !ok {
+		t.Errorf("SetClock(nil) clock = %T, expected realClock", c.clock)
+	}
+}
+
+func TestDo_circuitBreakerCooldownClock(t *testing.T) {
+	c := NewClient()
+	clock := newFakeClock()
+	SetClock(clock)(c)
+	SetCircuitBreaker(NewCircuitBreaker(1, 30*time.Second))(c)
+
+	c.breaker.record(false, clock.Now())
+
+	// The cooldown is measured on the client clock, without any real sleep.
+	for _, step := range []struct {
+		advance  time.Duration
+		expected error
+	}{
+		{0, ErrCircuitOpen},
+		{29 * time.Second, ErrCircuitOpen},
+		{time.Second, nil},
+	} {
+		clock.Advance(step.advance)
+		if err := c.breaker.allow(c.clock.Now()); err != step.expected {
+			t.Errorf("allow() at %v = %v, expected %v", clock.Now(), err, step.expected)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package directory
+
+import (
+	"net/http"
+	"time"
+)
+
+// redacted replaces the value of a credential in a ClientConfig.
+const redacted = "REDACTED"
+
+// ClientConfig is a snapshot of the effective, non-secret settings of a Client, meant to be logged or attached to
+// support requests. Credentials are never included: Token only tells whether one is set.
+type ClientConfig struct {
+	// BaseURL with any password replaced by "xxxxx".
+	BaseURL   string `json:"baseURL"`
+	UserAgent string `json:"userAgent"`
+
+	Host            string `json:"host,omitempty"`
+	AcceptLanguage  string `json:"acceptLanguage,omitempty"`
+	UsersPathPrefix string `json:"usersPathPrefix,omitempty"`
+
+	// Resource names of the Users endpoints set with SetUsersResourceNames.
+	UsersElement    string `json:"usersElement"`
+	UsersCollection string `json:"usersCollection"`
+
+	// Timeout of the underlying HTTP client, no timeout when zero.
+	Timeout           time.Duration `json:"timeout"`
+	PerRequestTimeout time.Duration `json:"perRequestTimeout"`
+
+	// ResponseHeaderTimeout of the transport set with SetResponseHeaderTimeout, no limit when zero.
+	ResponseHeaderTimeout time.Duration `json:"responseHeaderTimeout"`
+
+	// MaxRedirects set with SetMaxRedirects, nil when the net/http default applies.
+	MaxRedirects *int `json:"maxRedirects,omitempty"`
+
+	RetryMax        int           `json:"retryMax"`
+	RetryBackoff    time.Duration `json:"retryBackoff"`
+	RetryMaxBackoff time.Duration `json:"retryMaxBackoff"`
+	RetryFactor     float64       `json:"retryFactor"`
+	RetryJitter     float64       `json:"retryJitter"`
+
+	// RetryNonIdempotent reports whether requests that are not idempotent are retried too.
+	RetryNonIdempotent bool `json:"retryNonIdempotent"`
+
+	BulkConcurrency int `json:"bulkConcurrency"`
+
+	// Token is "REDACTED" when a bearer token is set with SetToken, empty otherwise.
+	Token string `json:"token,omitempty"`
+
+	// TokenSource reports whether tokens come from a TokenSource.
+	TokenSource bool `json:"tokenSource"`
+
+	DryRun bool `json:"dryRun"`
+}
+
+// Config returns a snapshot of the effective configuration of c with its credentials redacted.
+func (c *Client) Config() ClientConfig {
+	cfg := ClientConfig{
+		UserAgent:          c.UserAgent,
+		Host:               c.host,
+		AcceptLanguage:     c.acceptLanguage,
+		UsersPathPrefix:    c.usersPrefix,
+		UsersElement:       c.usersElement,
+		UsersCollection:    c.usersCollection,
+		PerRequestTimeout:  c.perRequestTimeout,
+		RetryMax:           c.retry.max,
+		RetryBackoff:       c.retry.backoff,
+		RetryMaxBackoff:    c.retry.maxBackoff,
+		RetryFactor:        c.retry.factor,
+		RetryJitter:        c.retry.jitter,
+		RetryNonIdempotent: c.retryNonIdempotent,
+		BulkConcurrency:    c.bulkConcurrency,
+		TokenSource:        c.tokens != nil,
+		DryRun:             c.dryRun != nil,
+	}
+
+	if c.BaseURL != nil {
+		cfg.BaseURL = c.BaseURL.Redacted()
+	}
+	if c.client != nil {
+		cfg.Timeout = c.client.Timeout
+		if t, ok := c.client.Transport.(*http.Transport); ok {
+			cfg.ResponseHeaderTimeout = t.ResponseHeaderTimeout
+		}
+	}
+	if c.maxRedirects != nil {
+		n := *c.maxRedirects
+		cfg.MaxRedirects = &n
+	}
+	if c.token != "" {
+		cfg.Token = redacted
+	}
+
+	return cfg
+}
@@ -0,0 +1,110 @@
+package directory
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+	userType       = reflect.TypeOf(User{})
+)
+
+// checkUnknownFields returns an error naming the first key of a JSON object in data that has no matching field in
+// the type t it was decoded into. Keys match a field by its JSON name, ignoring case, or by the snake_case form of
+// that name or a registered alias which User accepts. Data that does not fit t is left for the decoder to report.
+func checkUnknownFields(data []byte, t reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == rawMessageType {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(data, &object); err != nil || object == nil {
+			return nil
+		}
+
+		fields := structFields(t)
+		for key, value := range object {
+			f, ok := fields[strings.ToLower(key)]
+			if !ok {
+				f, ok = fields[strings.ToLower(camelCase(key))]
+			}
+			if !ok && t == userType {
+				f, ok = fields[strings.ToLower(userFieldName(key))]
+			}
+			if !ok {
+				return fmt.Errorf("json: unknown field %q in %v", key, t.Name())
+			}
+
+			if err := checkUnknownFields(value, f.Type); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		var elems []json.RawMessage
+		if err := json.Unmarshal(data, &elems); err != nil {
+			return nil
+		}
+
+		for _, elem := range elems {
+			if err := checkUnknownFields(elem, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil
+		}
+
+		for _, value := range values {
+			if err := checkUnknownFields(value, t.Elem()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// structFields returns the exported fields of the struct type t, including promoted ones, keyed by their
+// lowercased JSON name.
+func structFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range structFields(ft) {
+					if _, ok := fields[k]; !ok {
+						fields[k] = v
+					}
+				}
+				continue
+			}
+		}
+
+		if f.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f
+	}
+
+	return fields
+}
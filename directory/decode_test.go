@@ -0,0 +1,79 @@
+package directory
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestUsers_Get_strictDecoding(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"coreId":"aeg095","full_name":"Erick Guevara","id":"erick","title":"Engineer"}`)
+	})
+
+	// Lenient by default.
+	if _, _, err := client.Users.Get(ctx, user, nil); err != nil {
+		t.Errorf("Get() returned error: %v", err)
+	}
+
+	SetStrictDecoding(true)(client)
+
+	_, _, err := client.Users.Get(ctx, user, nil)
+	if err == nil || err.Error() != `json: unknown field "title" in User` {
+		t.Errorf("Get() strict error = %v, expected unknown field title", err)
+	}
+}
+
+func TestDo_strictDecoding(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"employees":[{"id":"a"},{"id":"b","title":"Engineer"}],"nextCursor":""}`)
+	})
+
+	SetStrictDecoding(true)(client)
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	_, err := client.Do(ctx, req, new(usersRoot))
+	if err == nil || err.Error() != `json: unknown field "title" in User` {
+		t.Errorf("Do() strict error = %v, expected unknown field title", err)
+	}
+}
+
+func TestCheckUnknownFields(t *testing.T) {
+	type inner struct {
+		Name string `json:"name"`
+	}
+	type outer struct {
+		inner
+		ID    string           `json:"id"`
+		Items []inner          `json:"items"`
+		Index map[string]inner `json:"index"`
+		Raw   json.RawMessage  `json:"raw"`
+		Skip  string           `json:"-"`
+	}
+
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{`{"name":"n","ID":"i","items":[{"name":"a"}],"index":{"k":{"name":"b"}},"raw":{"any":1}}`, ""},
+		{`{"Skip":"s"}`, `json: unknown field "Skip" in outer`},
+		{`{"items":[{"name":"a"},{"other":1}]}`, `json: unknown field "other" in inner`},
+		{`{"index":{"k":{"other":1}}}`, `json: unknown field "other" in inner`},
+		{`"not an object"`, ""},
+	}
+
+	for _, tt := range tests {
+		err := checkUnknownFields([]byte(tt.data), reflect.TypeOf(&outer{}))
+		if got := fmt.Sprint(err); (err == nil && tt.expected != "") || (err != nil && got != tt.expected) {
+			t.Errorf("checkUnknownFields(%s) = %v, expected %q", tt.data, err, tt.expected)
+		}
+	}
+}
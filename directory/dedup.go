@@ -0,0 +1,95 @@
+package directory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrDuplicateRequest is returned by Do instead of sending a write identical to one still in flight or sent within
+// the window configured with SetWriteDedup.
+var ErrDuplicateRequest = errors.New("duplicate request suppressed")
+
+// writeDedup tracks recent writes by a hash of their method, URL and body.
+type writeDedup struct {
+	window time.Duration
+
+	mu sync.Mutex
+	// Time each write completed; the zero time while it is in flight.
+	writes map[string]time.Time
+}
+
+// SetWriteDedup is a client option for suppressing accidental duplicate writes. A POST, PUT, PATCH or DELETE with
+// the same method, URL and body as one still in flight, or completed with a 2xx response less than window ago,
+// fails with ErrDuplicateRequest without reaching the server.
+func SetWriteDedup(window time.Duration) ClientOpt {
+	return func(c *Client) error {
+		c.dedup = &writeDedup{window: window, writes: make(map[string]time.Time)}
+		return nil
+	}
+}
+
+// writeKey returns the dedup key of req, or false when req is not a write or its body can not be read again.
+func writeKey(req *http.Request) (string, bool) {
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return "", false
+	}
+
+	h := sha256.New()
+	io.WriteString(h, req.Method+" "+req.URL.String()+"\n")
+
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return "", false
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return "", false
+		}
+		defer body.Close()
+
+		if _, err := io.Copy(h, body); err != nil {
+			return "", false
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// start registers the write with key at now, reporting false when it duplicates a recent one.
+func (d *writeDedup) start(key string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for k, done := range d.writes {
+		if !done.IsZero() && now.Sub(done) >= d.window {
+			delete(d.writes, k)
+		}
+	}
+
+	if _, ok := d.writes[key]; ok {
+		return false
+	}
+	d.writes[key] = time.Time{}
+
+	return true
+}
+
+// finish marks the write with key as completed at now with the response status, 0 when it failed without one. Only
+// a 2xx response starts its dedup window; a failed write may be sent again straight away.
+func (d *writeDedup) finish(key string, status int, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.window <= 0 || status < 200 || status > 299 {
+		delete(d.writes, key)
+		return
+	}
+	d.writes[key] = now
+}
@@ -0,0 +1,117 @@
+package directory
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDo_writeDedup(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var hits int32
+	arrived := make(chan struct{})
+	release := make(chan struct{})
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			close(arrived)
+		}
+		<-release
+	})
+
+	clock := newFakeClock()
+	SetClock(clock)(client)
+	SetWriteDedup(time.Second)(client)
+
+	create := func() error {
+		req, _ := client.NewRequest("POST", "employee", &User{ID: "erick"})
+		_, err := client.Do(ctx, req, nil)
+		return err
+	}
+
+	first := make(chan error)
+	go func() { first <- create() }()
+	<-arrived
+
+	if err := create(); err != ErrDuplicateRequest {
+		t.Errorf("Do() duplicate in flight = %v, expected %v", err, ErrDuplicateRequest)
+	}
+
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatalf("Do() returned error: %v", err)
+	}
+
+	if err := create(); err != ErrDuplicateRequest {
+		t.Errorf("Do() duplicate within the window = %v, expected %v", err, ErrDuplicateRequest)
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("Do() duplicates reached the server, hits = %d", got)
+	}
+
+	// A different body is not a duplicate.
+	req, _ := client.NewRequest("POST", "employee", &User{ID: "other"})
+	if _, err := client.Do(ctx, req, nil); err != nil {
+		t.Errorf("Do() returned error for a different write: %v", err)
+	}
+
+	clock.Advance(time.Second)
+	if err := create(); err != nil {
+		t.Errorf("Do() returned error after the window: %v", err)
+	}
+}
+
+func TestDo_writeDedupFailed(t *testing.T) {
+	setup()
+	defer teardown()
+
+	hits := 0
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	SetClock(newFakeClock())(client)
+	SetWriteDedup(time.Second)(client)
+
+	create := func() error {
+		req, _ := client.NewRequest("POST", "employee", &User{ID: "erick"})
+		_, err := client.Do(ctx, req, nil)
+		return err
+	}
+
+	if err := create(); err == nil {
+		t.Fatalf("Do() expected error for a 503 response")
+	}
+
+	// The directory rejected the write, so sending it again is not a duplicate.
+	if err := create(); err != nil {
+		t.Errorf("Do() retry of a failed write returned error: %v", err)
+	}
+	if err := create(); err != ErrDuplicateRequest {
+		t.Errorf("Do() duplicate of a successful write = %v, expected %v", err, ErrDuplicateRequest)
+	}
+	if hits != 2 {
+		t.Errorf("Do() hits = %d, expected 2", hits)
+	}
+}
+
+func TestDo_writeDedupReads(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {})
+
+	SetWriteDedup(time.Minute)(client)
+
+	for i := 0; i < 2; i++ {
+		req, _ := client.NewRequest("GET", "employee", nil)
+		if _, err := client.Do(ctx, req, nil); err != nil {
+			t.Errorf("Do() GET returned error: %v", err)
+		}
+	}
+}
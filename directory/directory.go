@@ -2,15 +2,23 @@ package directory
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"reflect"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/google/go-querystring/query"
 )
@@ -19,6 +27,10 @@ const (
 	libraryVersion = "1.0.0"
 	userAgent      = "go-directory/" + libraryVersion
 	mediaType      = "application/json"
+
+	defaultBulkConcurrency = 10
+
+	defaultUsersResource = "employee"
 )
 
 // Client manages communication with directory V2 API.
@@ -26,12 +38,115 @@ type Client struct {
 	// HTTP client used to communicate with the directory API.
 	client *http.Client
 
+	// Timeout set with SetTimeout, kept so it survives a later SetHTTPClient.
+	timeout *time.Duration
+
+	// Redirect limit set with SetMaxRedirects, kept so it survives a later SetHTTPClient.
+	maxRedirects *int
+
+	// Transport options, kept so they survive a later SetHTTPClient.
+	transportSettings transportSettings
+
+	// Wraps the transport of every request, such as for SetRecording and SetReplay, when set.
+	transportWrap func(http.RoundTripper) http.RoundTripper
+
 	// Base URL for API requests.
 	BaseURL *url.URL
 
 	// User agent for client
 	UserAgent string
 
+	// Host header sent instead of the host of the request URL when set.
+	host string
+
+	// Default Accept-Language header sent with every request.
+	acceptLanguage string
+
+	// Bearer token sent in the Authorization header.
+	token string
+
+	// Source of bearer tokens used instead of token when set.
+	tokens *tokenCache
+
+	// Optional hook signing every request built by NewRequest.
+	signer func(req *http.Request, body []byte) error
+
+	// Maximum number of concurrent requests issued by bulk methods.
+	bulkConcurrency int
+
+	// Time limit of each request issued by bulk methods, no limit when zero.
+	perRequestTimeout time.Duration
+
+	// Maximum number of managers resolved by Users.ManagementChain, defaultMaxManagementDepth when zero.
+	maxManagementDepth int
+
+	// Source of the current time.
+	clock Clock
+
+	// Optional circuit breaker guarding every request.
+	breaker *CircuitBreaker
+
+	// Retry settings, no retries by default.
+	retry retryPolicy
+
+	// Retry requests that are not idempotent too.
+	retryNonIdempotent bool
+
+	// Optional limit on the retries of all requests.
+	retryBudget *retryBudget
+
+	// Optional suppression of duplicate writes.
+	dedup *writeDedup
+
+	// Captures requests instead of sending them when set.
+	dryRun *dryRun
+
+	// Root of the request contexts, cancelled by CancelAll.
+	cancels cancelGroup
+
+	// Deduplicates concurrent Users.Get calls when set.
+	getFlight *flightGroup
+
+	// Check field selections against the known User fields before sending requests.
+	validateFields bool
+
+	// Reject responses that carry fields the decoded type does not model.
+	strictDecoding bool
+
+	// Decoder used instead of encoding/json when set.
+	decoder func(r io.Reader, v interface{}) error
+
+	// Treat successful responses carrying an error envelope as errors.
+	checkErrorEnvelope bool
+
+	// Treat successful responses holding an empty object as errors.
+	rejectEmpty bool
+
+	// Warn when a user response does not match the fields selection of the request.
+	verifyProjection bool
+
+	// Destination of warnings, discarded when nil.
+	logger Logger
+
+	// Receives a copy of every response body read by Do when set.
+	responseTap io.Writer
+
+	// Negotiate and decompress gzip responses in Do instead of the transport.
+	disableCompression bool
+
+	// Runs on every decoded response when set.
+	responseMiddleware ResponseMiddleware
+
+	// Receives an entry for every mutation when set.
+	auditSink func(AuditEntry)
+
+	// Path under the BaseURL that the Users service endpoints live in, the BaseURL itself when empty.
+	usersPrefix string
+
+	// Resource names of the Users endpoints for a single employee and for the collection.
+	usersElement    string
+	usersCollection string
+
 	// Reuse a single struct instead of allocating one for each service on the heap.
 	common service
 
@@ -46,6 +161,23 @@ type service struct {
 // Response is a directory response. This wraps the standard http.Response returned from Directory.
 type Response struct {
 	*http.Response
+
+	// NextCursor is the opaque cursor for the next page of a list call. An empty
+	// NextCursor means there are no more results.
+	NextCursor string
+
+	// Total is the number of results of a list call across all pages, when the API reports it.
+	Total *int
+
+	// Trace holds the phase timings of a request sent with WithTrace.
+	Trace *Trace
+
+	// Attempts is the number of times the request was sent, 1 when the first attempt got the response. Retries and
+	// the resend after refreshing an expired token both count.
+	Attempts int
+
+	// Body read by Do to decode v, kept for Decode.
+	body []byte
 }
 
 // An ErrorResponse reports the error caused by an API request
@@ -60,12 +192,157 @@ type ErrorResponse struct {
 
 // CustomError holds directory error response.
 type CustomError struct {
-	Code    int    `json:"code,omitempty"`
+	Code    int           `json:"code,omitempty"`
+	Message string        `json:"message,omitempty"`
+	Errors  []ErrorDetail `json:"errors,omitempty"`
+}
+
+// ErrorDetail holds one entry of the errors list in a directory error response.
+type ErrorDetail struct {
+	Domain  string `json:"domain,omitempty"`
+	Reason  string `json:"reason,omitempty"`
 	Message string `json:"message,omitempty"`
+	Field   string `json:"field,omitempty"`
+}
+
+// Known values of ErrorDetail.Reason.
+const (
+	ReasonBadRequest        = "badRequest"
+	ReasonInvalid           = "invalid"
+	ReasonRequired          = "required"
+	ReasonNotFound          = "notFound"
+	ReasonAlreadyExists     = "alreadyExists"
+	ReasonForbidden         = "forbidden"
+	ReasonRateLimitExceeded = "rateLimitExceeded"
+	ReasonBackendError      = "backendError"
+)
+
+// Known values of ErrorDetail.Domain.
+const (
+	DomainGlobal      = "global"
+	DomainUsageLimits = "usageLimits"
+)
+
+// HasReason reports whether any error detail of r has the given reason, such as ReasonBadRequest, so callers can
+// branch on the reason instead of the message text.
+func (r *ErrorResponse) HasReason(reason string) bool {
+	for _, e := range r.Errors {
+		if e.Reason == reason {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (r *ErrorResponse) Error() string {
-	return fmt.Sprintf("%v", r.CustomError.Message)
+	message := r.CustomError.Message
+	if message == "" && r.Response != nil {
+		// Gateway errors often come without a directory error envelope.
+		message = r.Response.Status
+		if message == "" {
+			message = fmt.Sprintf("%d %s", r.Response.StatusCode, http.StatusText(r.Response.StatusCode))
+		}
+	}
+
+	if r.Response != nil && r.Response.Request != nil && r.Response.Request.URL != nil {
+		req := r.Response.Request
+		return fmt.Sprintf("%v %v: %v", req.Method, req.URL.Path, message)
+	}
+
+	return fmt.Sprintf("%v", message)
+}
+
+// FieldErrors maps the field of each error detail to its message, so validation messages can be shown next to
+// the offending field. Details without a field are keyed by their reason.
+func (r *ErrorResponse) FieldErrors() map[string]string {
+	fields := make(map[string]string, len(r.Errors))
+	for _, e := range r.Errors {
+		key := e.Field
+		if key == "" {
+			key = e.Reason
+		}
+		fields[key] = e.Message
+	}
+
+	return fields
+}
+
+// IsHTTP2 reports whether the response was received over HTTP/2. The negotiated protocol is available as Proto.
+func (r *Response) IsHTTP2() bool {
+	return r.ProtoMajor == 2
+}
+
+// IsAccepted reports whether the API answered 202 Accepted, meaning the operation was queued and will complete
+// asynchronously.
+func (r *Response) IsAccepted() bool {
+	return r.StatusCode == http.StatusAccepted
+}
+
+// NextPageURL returns the URL of the rel="next" link in the Link header, or an empty string when there is no next
+// page. A relative target, such as </employee?page=2>, is resolved against the URL of the request.
+func (r *Response) NextPageURL() string {
+	if r.Response == nil {
+		return ""
+	}
+
+	for _, header := range r.Header["Link"] {
+		for _, link := range strings.Split(header, ",") {
+			segments := strings.Split(link, ";")
+			target := strings.TrimSpace(segments[0])
+			if len(segments) < 2 || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, param := range segments[1:] {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				if len(kv) != 2 || strings.ToLower(kv[0]) != "rel" {
+					continue
+				}
+
+				for _, rel := range strings.Fields(strings.Trim(kv[1], `"`)) {
+					if rel == "next" {
+						return r.resolveLink(target[1 : len(target)-1])
+					}
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// resolveLink resolves the Link target ref against the URL of the request, leaving it as is when either is unknown.
+func (r *Response) resolveLink(ref string) string {
+	u, err := url.Parse(ref)
+	if err != nil || u.IsAbs() || r.Request == nil || r.Request.URL == nil {
+		return ref
+	}
+
+	return r.Request.URL.ResolveReference(u).String()
+}
+
+// ConfigError reports a client misconfiguration detected by Validate.
+type ConfigError struct {
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid client configuration: %v", e.Message)
+}
+
+// ConnectionError reports that the directory API could not be reached by Validate.
+type ConnectionError struct {
+	Err error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("unable to reach directory API: %v", e.Err)
+}
+
+// Unwrap returns the underlying transport error.
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
 }
 
 func addOptions(s string, opt interface{}) (string, error) {
@@ -93,13 +370,17 @@ func NewClient() *Client {
 
 	httpClient := http.DefaultClient
 
-	c := &Client{client: httpClient, UserAgent: userAgent}
+	c := &Client{client: httpClient, UserAgent: userAgent, bulkConcurrency: defaultBulkConcurrency, clock: realClock{},
+		retry: defaultRetryPolicy(), usersElement: defaultUsersResource, usersCollection: defaultUsersResource}
 	c.common.client = c
 	c.Users = (*UsersServiceOp)(&c.common)
 
 	return c
 }
 
+// ErrNoBaseURL is returned by New when no option set the base URL.
+var ErrNoBaseURL = errors.New("you need to set the baseURL with the SetBaseURL helper")
+
 // ClientOpt are options for New.
 type ClientOpt func(*Client) error
 
@@ -113,7 +394,7 @@ func New(opts ...ClientOpt) (*Client, error) {
 	}
 
 	if c.BaseURL == nil {
-		return nil, errors.New("you need to set the baseURL with the SetBaseURL helper")
+		return nil, ErrNoBaseURL
 	}
 
 	return c, nil
@@ -127,142 +408,1300 @@ func SetBaseURL(bu string) ClientOpt {
 			return err
 		}
 
+		// url.Parse accepts "localhost:8080" as an opaque URL with the scheme "localhost", which only fails later
+		// when requests are sent.
+		switch {
+		case u.Scheme != "http" && u.Scheme != "https":
+			return &ConfigError{Message: fmt.Sprintf("base URL %q must use the http or https scheme", bu)}
+		case u.Host == "":
+			return &ConfigError{Message: fmt.Sprintf("base URL %q has no host", bu)}
+		}
+
 		c.BaseURL = u
 		return nil
 	}
 }
 
-// SetHTTPClient makes the directory client use the given HTTP client.
+// SetHTTPClient makes the directory client use the given HTTP client. A timeout set with SetTimeout or a limit set
+// with SetMaxRedirects is applied to a copy of client, whichever option comes first; so are the transport options,
+// such as SetResponseHeaderTimeout, which then require client to use an *http.Transport. SetRecording and SetReplay
+// wrap the transport of client whatever the option order.
 func SetHTTPClient(client *http.Client) ClientOpt {
 	return func(c *Client) error {
 		if client == nil {
 			// for some silly reason you send a nil client
-			c.client = http.DefaultClient
-			return nil
+			client = http.DefaultClient
 		}
 		c.client = client
 
+		if c.timeout != nil || c.maxRedirects != nil {
+			c.applyClientSettings()
+		}
+		if c.transportSettings.isSet() {
+			t, err := c.transport()
+			if err != nil {
+				return err
+			}
+			c.transportSettings.apply(t)
+		}
 		return nil
 	}
 }
 
-// SetUserAgent is a client option for setting the user agent.
-func SetUserAgent(ua string) ClientOpt {
+// SetTimeout is a client option for setting the time limit of each HTTP request, including reading the response body.
+func SetTimeout(d time.Duration) ClientOpt {
 	return func(c *Client) error {
-		c.UserAgent = fmt.Sprintf("%s+%s", ua, c.UserAgent)
+		c.timeout = &d
+		c.applyClientSettings()
+
 		return nil
 	}
 }
 
-// NewRequest creates an API request. A relative URL can be provided in urlStr, which will be resolved to the
-// BaseURL of the Client. Relative URLS should always be specified without a preceding slash. If specified, the
-// value pointed to by body is JSON encoded and included in as the request body.
-func (c *Client) NewRequest(method, urlStr string, body interface{}) (*http.Request, error) {
-	rel, err := url.Parse(urlStr)
-	if err != nil {
-		return nil, err
-	}
+// ErrTooManyRedirects is returned, wrapped in a *url.Error, when a request is redirected more times than allowed by
+// SetMaxRedirects.
+var ErrTooManyRedirects = errors.New("stopped after too many redirects")
 
-	u := c.BaseURL.ResolveReference(rel)
+// SetMaxRedirects is a client option for following at most n redirects per request, instead of the 10 of the
+// default HTTP client, before failing with ErrTooManyRedirects. With a negative n redirects are not followed at all
+// and the redirect response is returned as an error response.
+func SetMaxRedirects(n int) ClientOpt {
+	return func(c *Client) error {
+		c.maxRedirects = &n
+		c.applyClientSettings()
 
-	var buf io.ReadWriter
-	if body != nil {
-		buf = new(bytes.Buffer)
-		err := json.NewEncoder(buf).Encode(body)
-		if err != nil {
-			return nil, err
+		return nil
+	}
+}
+
+// applyClientSettings gives the client its own copy of the HTTP client with the timeout and redirect limit set
+// through client options.
+func (c *Client) applyClientSettings() {
+	hc := *c.client
+	if c.timeout != nil {
+		hc.Timeout = *c.timeout
+	}
+	if c.maxRedirects != nil {
+		max := *c.maxRedirects
+		hc.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if max < 0 {
+				return http.ErrUseLastResponse
+			}
+			if len(via) > max {
+				return ErrTooManyRedirects
+			}
+			return nil
 		}
 	}
+	c.client = &hc
+}
 
-	req, err := http.NewRequest(method, u.String(), buf)
-	if err != nil {
-		return nil, err
+// SetUsersPathPrefix is a client option for serving the Users endpoints from prefix under the BaseURL, such as
+// "hr" for BaseURL/hr/employee, instead of from the BaseURL itself.
+func SetUsersPathPrefix(prefix string) ClientOpt {
+	return func(c *Client) error {
+		c.usersPrefix = strings.Trim(prefix, "/")
+		return nil
 	}
+}
 
-	req.Header.Add("Content-Type", mediaType)
-	req.Header.Add("Accept", mediaType)
-	req.Header.Add("User-Agent", c.UserAgent)
+// SetUsersResourceNames is a client option for the resource names of the Users endpoints, for backends that serve
+// a single employee and the collection from different paths, such as BaseURL/employee/erick for Get and
+// BaseURL/employees for List. Get uses element; the collection endpoints (List, Count, GetByEmail, Create and the
+// bulk endpoint) use collection. Both default to "employee".
+func SetUsersResourceNames(element, collection string) ClientOpt {
+	return func(c *Client) error {
+		element, collection = strings.Trim(element, "/"), strings.Trim(collection, "/")
+		if element == "" || collection == "" {
+			return fmt.Errorf("users resource names can not be empty, got %q and %q", element, collection)
+		}
 
-	// out, err := httputil.DumpRequestOut(req, true)
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
-	// fmt.Println(strings.Replace(string(out), "\r", "", -1))
-	// fmt.Println("--newRequest--")
+		c.usersElement, c.usersCollection = element, collection
+		return nil
+	}
+}
 
-	return req, nil
+// SetHost is a client option for sending host in the Host header of every request, for virtual-host routing, while
+// connecting to the host of the BaseURL.
+func SetHost(host string) ClientOpt {
+	return func(c *Client) error {
+		c.host = host
+		return nil
+	}
 }
 
-// newResponse creates a new Response for the provided http.Response
-func newResponse(r *http.Response) *Response {
-	response := Response{Response: r}
+// SetToken is a client option for authenticating every request with the given bearer token.
+func SetToken(token string) ClientOpt {
+	return func(c *Client) error {
+		c.token = token
+		return nil
+	}
+}
 
-	return &response
+// SetSigner is a client option for signing every request, for example with an HMAC header required by a partner
+// directory. NewRequest calls signer once the request is built, with the encoded body bytes (nil without a body).
+// Request bodies are buffered when a signer is set so they stay rewindable.
+func SetSigner(signer func(req *http.Request, body []byte) error) ClientOpt {
+	return func(c *Client) error {
+		c.signer = signer
+		return nil
+	}
 }
 
-// Do sends an API request and returns the API response. The API response is JSON decoded and stored in the value
-// pointed to by v, or returned as an error if an API error has occurred. If v implements the io.Writer interface,
-// the raw response will be written to v, without attempting to decode it.
-func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*Response, error) {
-	req = req.WithContext(ctx)
+// SetHTTP2 toggles HTTP/2 on the client transport. When enabled HTTP/2 is attempted on every TLS connection, even
+// for customised transports; when disabled the client sticks to HTTP/1.1.
+func SetHTTP2(enabled bool) ClientOpt {
+	return func(c *Client) error {
+		return c.setTransport(func(s *transportSettings) { s.http2 = &enabled })
+	}
+}
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, err
+// SetDisableCompression is a client option for turning off the transparent compression of the transport, which
+// otherwise requests gzip and decompresses responses on its own unless an Accept-Encoding header is set. When
+// disabled the client negotiates compression itself instead: requests without an Accept-Encoding header ask for
+// gzip, and Do decompresses gzip encoded responses exactly once, whatever Accept-Encoding was sent.
+func SetDisableCompression(disabled bool) ClientOpt {
+	return func(c *Client) error {
+		if err := c.setTransport(func(s *transportSettings) { s.disableCompression = &disabled }); err != nil {
+			return err
+		}
+
+		c.disableCompression = disabled
+		return nil
 	}
-	defer func() {
-		if rerr := resp.Body.Close(); err == nil {
-			err = rerr
+}
+
+// SetResponseHeaderTimeout is a client option for limiting the time to wait for the response headers once the
+// request is written, so a server that stalls before answering fails fast. Unlike SetTimeout it does not bound the
+// time spent reading the body afterwards. Zero means no limit.
+func SetResponseHeaderTimeout(d time.Duration) ClientOpt {
+	return func(c *Client) error {
+		if d < 0 {
+			return fmt.Errorf("response header timeout can not be negative, got %v", d)
 		}
-	}()
 
-	response := newResponse(resp)
+		return c.setTransport(func(s *transportSettings) { s.responseHeaderTimeout = &d })
+	}
+}
 
-	// outResp, err := httputil.DumpResponse(resp, true)
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
-	// fmt.Println(strings.Replace(string(outResp), "\r", "", -1))
-	// fmt.Println("-Do---")
+// SetMaxResponseHeaderBytes is a client option for limiting the size of the response headers the transport reads,
+// so a misbehaving backend can not exhaust memory with a pathological set of headers. A response over the limit
+// fails the request. Zero keeps the net/http default of 10MB.
+func SetMaxResponseHeaderBytes(n int64) ClientOpt {
+	return func(c *Client) error {
+		if n < 0 {
+			return fmt.Errorf("max response header bytes can not be negative, got %d", n)
+		}
 
-	err = CheckResponse(resp)
-	if err != nil {
-		return response, err
+		return c.setTransport(func(s *transportSettings) { s.maxResponseHeaderBytes = &n })
 	}
+}
 
-	if v != nil {
-		if w, ok := v.(io.Writer); ok {
-			_, err := io.Copy(w, resp.Body)
-			if err != nil {
-				return nil, err
-			}
+// transportSettings holds the transport options set on a Client, kept so they survive a later SetHTTPClient. Nil
+// fields were never set and leave the transport as is.
+type transportSettings struct {
+	http2                  *bool
+	disableCompression     *bool
+	responseHeaderTimeout  *time.Duration
+	maxResponseHeaderBytes *int64
+}
+
+// isSet reports whether any transport option was set.
+func (s transportSettings) isSet() bool {
+	return s.http2 != nil || s.disableCompression != nil || s.responseHeaderTimeout != nil ||
+		s.maxResponseHeaderBytes != nil
+}
+
+// apply configures t with the options that were set.
+func (s transportSettings) apply(t *http.Transport) {
+	if s.http2 != nil {
+		t.ForceAttemptHTTP2 = *s.http2
+		if *s.http2 {
+			t.TLSNextProto = nil
 		} else {
-			err := json.NewDecoder(resp.Body).Decode(v)
-			if err != io.EOF {
-				err = nil // ignore EOF
-			}
+			// A non-nil, empty map disables HTTP/2.
+			t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
 		}
 	}
+	if s.disableCompression != nil {
+		t.DisableCompression = *s.disableCompression
+	}
+	if s.responseHeaderTimeout != nil {
+		t.ResponseHeaderTimeout = *s.responseHeaderTimeout
+	}
+	if s.maxResponseHeaderBytes != nil {
+		t.MaxResponseHeaderBytes = *s.maxResponseHeaderBytes
+	}
+}
 
-	return response, err
+// setTransport records the transport option set by update and applies every recorded option to the transport of
+// the client.
+func (c *Client) setTransport(update func(*transportSettings)) error {
+	t, err := c.transport()
+	if err != nil {
+		return err
+	}
+
+	update(&c.transportSettings)
+	c.transportSettings.apply(t)
+	return nil
 }
 
-// CheckResponse checks the API response for errors, and returns them if present. A response is considered an
-// error if it has a status code outside the 200 range. API error responses are expected to have either no response
-// body, or a JSON response body that maps to ErrorResponse. Any other response body will be silently ignored.
-func CheckResponse(r *http.Response) error {
-	if c := r.StatusCode; c >= 200 && c <= 299 {
+// decompress replaces the body of a gzip encoded resp, not already decompressed by the transport, with its
+// decompressed content, updating the headers like the transport does.
+func decompress(resp *http.Response) error {
+	if resp.Uncompressed || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
 		return nil
 	}
 
-	errorResponse := &ErrorResponse{Response: r}
-	data, err := ioutil.ReadAll(r.Body)
-	if err == nil && len(data) > 0 {
-		err := json.Unmarshal(data, errorResponse)
-		if err != nil {
-			return err
+	zr, err := gzip.NewReader(resp.Body)
+	switch {
+	case err == io.EOF:
+		// An empty body, such as that of a 204, has nothing to decompress.
+		return nil
+	case err != nil:
+		return fmt.Errorf("decompressing response: %w", err)
+	}
+
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{zr, resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return nil
+}
+
+// transport gives the client its own copy of the HTTP client and *http.Transport and returns the transport so
+// options can tune it without mutating http.DefaultClient, http.DefaultTransport or a caller supplied client.
+func (c *Client) transport() (*http.Transport, error) {
+	rt := c.client.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("can not configure transport of type %T", rt)
+	}
+	t = t.Clone()
+
+	hc := *c.client
+	hc.Transport = t
+	c.client = &hc
+
+	return t, nil
+}
+
+// wrapTransport adds wrap around the transport of every request. Wrappers are applied by httpClient when a request
+// is sent, outside any earlier ones, so they survive SetHTTPClient and leave the *http.Transport tunable by later
+// options.
+func (c *Client) wrapTransport(wrap func(http.RoundTripper) http.RoundTripper) {
+	inner := c.transportWrap
+	if inner == nil {
+		c.transportWrap = wrap
+		return
+	}
+
+	c.transportWrap = func(rt http.RoundTripper) http.RoundTripper {
+		return wrap(inner(rt))
+	}
+}
+
+// httpClient returns the HTTP client requests are sent with: the configured one, with its transport wrapped by the
+// wrappers added with wrapTransport.
+func (c *Client) httpClient() *http.Client {
+	if c.transportWrap == nil {
+		return c.client
+	}
+
+	rt := c.client.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	hc := *c.client
+	hc.Transport = c.transportWrap(rt)
+	return &hc
+}
+
+// SetBulkConcurrency is a client option for setting how many requests bulk methods like Users.BulkGet run at once.
+func SetBulkConcurrency(n int) ClientOpt {
+	return func(c *Client) error {
+		if n < 1 {
+			return fmt.Errorf("bulk concurrency must be at least 1, got %d", n)
 		}
+
+		c.bulkConcurrency = n
+		return nil
 	}
+}
 
-	return errorResponse
+// SetPerRequestTimeout is a client option for bounding each request issued by bulk methods like Users.BulkGet to d,
+// independently of the deadline of the whole operation. A request that times out only fails its own mmID.
+func SetPerRequestTimeout(d time.Duration) ClientOpt {
+	return func(c *Client) error {
+		if d < 0 {
+			return fmt.Errorf("per request timeout can not be negative, got %v", d)
+		}
+
+		c.perRequestTimeout = d
+		return nil
+	}
+}
+
+// SetCoalesceGets is a client option for sharing a single in-flight request between concurrent Users.Get calls for
+// the same mmID and options. Each caller still gets its own copy of the user and can give up waiting by cancelling
+// its context.
+func SetCoalesceGets(enabled bool) ClientOpt {
+	return func(c *Client) error {
+		c.getFlight = nil
+		if enabled {
+			c.getFlight = newFlightGroup()
+		}
+
+		return nil
+	}
+}
+
+// SetValidateFields is a client option for checking the Fields of UsersOptions and UsersListOptions against the
+// known User fields before a request is sent, so a typo fails fast instead of being silently ignored by the API.
+// Leave it off when the backend schema is ahead of this client.
+func SetValidateFields(enabled bool) ClientOpt {
+	return func(c *Client) error {
+		c.validateFields = enabled
+		return nil
+	}
+}
+
+// SetDecoder is a client option for decoding response bodies with decoder, such as a faster JSON implementation,
+// instead of encoding/json. Do calls decoder with the buffered body, which stays available to Response.Decode, and
+// skips it for empty bodies and for values implementing io.Writer.
+func SetDecoder(decoder func(r io.Reader, v interface{}) error) ClientOpt {
+	return func(c *Client) error {
+		c.decoder = decoder
+		return nil
+	}
+}
+
+// SetCheckErrorEnvelope is a client option for treating a successful response whose body holds a non-empty
+// {"error":{...}} envelope, which some backends send with a 200, as an *ErrorResponse. Do then reads every response
+// body, even when called with a nil value.
+func SetCheckErrorEnvelope(enabled bool) ClientOpt {
+	return func(c *Client) error {
+		c.checkErrorEnvelope = enabled
+		return nil
+	}
+}
+
+// SetRejectEmptyResources is a client option for failing with ErrEmptyResource when a successful response holds an
+// empty JSON object, {}, which some backends return instead of a 404, rather than returning a zero value
+// indistinguishable from a real resource. It applies to single resources, such as the user returned by Users.Get
+// or Users.Create; an empty list response only means there are no results.
+func SetRejectEmptyResources(enabled bool) ClientOpt {
+	return func(c *Client) error {
+		c.rejectEmpty = enabled
+		return nil
+	}
+}
+
+// SetVerifyProjection is a client option for checking user responses against the fields selection of the request.
+// A warning is written to the logger set with SetLogger when a requested field is missing from the response or a
+// field that was not requested is present. The response is returned either way.
+func SetVerifyProjection(enabled bool) ClientOpt {
+	return func(c *Client) error {
+		c.verifyProjection = enabled
+		return nil
+	}
+}
+
+// SetStrictDecoding is a client option for rejecting responses whose JSON has fields that the value it is decoded
+// into does not model, such as a new field added to the employee resource. By default unknown fields are ignored.
+func SetStrictDecoding(strict bool) ClientOpt {
+	return func(c *Client) error {
+		c.strictDecoding = strict
+		return nil
+	}
+}
+
+// SetUserAgent is a client option for setting the user agent.
+func SetUserAgent(ua string) ClientOpt {
+	return func(c *Client) error {
+		c.UserAgent = fmt.Sprintf("%s+%s", ua, c.UserAgent)
+		return nil
+	}
+}
+
+// SetExactUserAgent is a client option for sending exactly ua in the User-Agent header, for gateways that reject
+// the "+" joined value built by SetUserAgent. It bypasses the compounding: the value set so far, including the
+// go-directory suffix, is replaced. A SetUserAgent applied after it prefixes ua again.
+func SetExactUserAgent(ua string) ClientOpt {
+	return func(c *Client) error {
+		c.UserAgent = ua
+		return nil
+	}
+}
+
+// SetAcceptLanguage is a client option for setting the Accept-Language header sent with every request. It can be
+// overridden for a single request with WithAcceptLanguage.
+func SetAcceptLanguage(lang string) ClientOpt {
+	return func(c *Client) error {
+		if err := validateLanguage(lang); err != nil {
+			return err
+		}
+
+		c.acceptLanguage = lang
+		return nil
+	}
+}
+
+// languageTag loosely matches a BCP 47 language tag such as "en", "fr-CA" or "zh-Hant-TW".
+var languageTag = regexp.MustCompile(`^[A-Za-z]{1,8}(-[A-Za-z0-9]{1,8})*$`)
+
+func validateLanguage(lang string) error {
+	if !languageTag.MatchString(lang) {
+		return fmt.Errorf("invalid language tag %q", lang)
+	}
+
+	return nil
+}
+
+// RequestOpt are options for a single request built by NewRequest.
+type RequestOpt func(*requestOptions) error
+
+// requestOptions holds the settings applied by RequestOpts to a single request.
+type requestOptions struct {
+	header   http.Header
+	progress ProgressFunc
+	expected []int
+
+	// Absolute URL used instead of resolving urlStr against BaseURL.
+	absoluteURL *url.URL
+
+	// Number of retries overriding the client one when set.
+	retryMax *int
+
+	// Record the phase timings of the request into Response.Trace.
+	trace bool
+
+	// Compress the request body with gzip.
+	gzip bool
+
+	// Already encoded JSON sent as the request body.
+	rawJSON []byte
+
+	// Decoded into an internal value, the response middleware is run by the caller.
+	intermediate bool
+}
+
+// requestOptionsKey is the context key NewRequest stores the request options under for Do.
+type requestOptionsKey struct{}
+
+// requestOptionsFrom returns the request options NewRequest attached to req.
+func requestOptionsFrom(req *http.Request) *requestOptions {
+	if ro, ok := req.Context().Value(requestOptionsKey{}).(*requestOptions); ok {
+		return ro
+	}
+
+	return &requestOptions{header: http.Header{}}
+}
+
+// isExpected reports whether code was registered with WithExpectedStatus.
+func (o *requestOptions) isExpected(code int) bool {
+	for _, c := range o.expected {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ProgressFunc is called while a response body is copied to an io.Writer with the number of bytes read so far and
+// the total size from Content-Length, or -1 when the size is unknown.
+type ProgressFunc func(bytesRead, total int64)
+
+// WithProgress is a request option for reporting the progress of a response body copied by Do to an io.Writer.
+func WithProgress(fn ProgressFunc) RequestOpt {
+	return func(o *requestOptions) error {
+		o.progress = fn
+		return nil
+	}
+}
+
+// WithAcceptLanguage is a request option for setting the Accept-Language header of a single request.
+func WithAcceptLanguage(lang string) RequestOpt {
+	return func(o *requestOptions) error {
+		if err := validateLanguage(lang); err != nil {
+			return err
+		}
+
+		o.header.Set("Accept-Language", lang)
+		return nil
+	}
+}
+
+// WithPrefer is a request option for sending value in the Prefer header (RFC 7240), such as PreferMinimal for the
+// API to skip echoing the object it stored.
+func WithPrefer(value string) RequestOpt {
+	return func(o *requestOptions) error {
+		o.header.Set("Prefer", value)
+		return nil
+	}
+}
+
+// Preferences of the return of a write sent with WithPrefer.
+const (
+	PreferMinimal        = "return=minimal"
+	PreferRepresentation = "return=representation"
+)
+
+// prefersMinimal reports whether req asks for a minimal response with WithPrefer.
+func prefersMinimal(req *http.Request) bool {
+	for _, v := range req.Header.Values("Prefer") {
+		for _, p := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(p), PreferMinimal) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// WithExpectedStatus is a request option for treating the given status codes as success, so Do returns the
+// response without an error even when the code is outside the 200 range (for example a 404 on an existence check).
+func WithExpectedStatus(codes ...int) RequestOpt {
+	return func(o *requestOptions) error {
+		o.expected = append(o.expected, codes...)
+		return nil
+	}
+}
+
+// requestIDHeader carries the ID correlating a request across services.
+const requestIDHeader = "X-Request-ID"
+
+// WithRequestID is a request option for sending id in the X-Request-ID header, instead of the random ID NewRequest
+// generates for every request.
+func WithRequestID(id string) RequestOpt {
+	return func(o *requestOptions) error {
+		o.header.Set(requestIDHeader, id)
+		return nil
+	}
+}
+
+// newRequestID returns a random request ID.
+func newRequestID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// WithRawJSON is a request option for sending b, already encoded JSON such as a cached payload, as the request
+// body with the JSON content type. NewRequest must then be called with a nil body. b must be valid JSON.
+func WithRawJSON(b []byte) RequestOpt {
+	return func(o *requestOptions) error {
+		if !json.Valid(b) {
+			return errors.New("WithRawJSON body is not valid JSON")
+		}
+
+		o.rawJSON = b
+		return nil
+	}
+}
+
+// WithGzipBody is a request option for compressing the request body with gzip and sending it with a
+// Content-Encoding: gzip header, for large uploads. The compressed body is buffered so it stays rewindable. A signer
+// set with SetSigner signs the compressed bytes.
+func WithGzipBody() RequestOpt {
+	return func(o *requestOptions) error {
+		o.gzip = true
+		return nil
+	}
+}
+
+// WithAbsoluteURL is a request option for sending the request to u verbatim, such as a pagination link returned
+// by the API, instead of resolving the urlStr given to NewRequest against BaseURL. The scheme and host of u must
+// match those of the BaseURL, and u must not carry user info.
+func WithAbsoluteURL(u string) RequestOpt {
+	return func(o *requestOptions) error {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return err
+		}
+		if !parsed.IsAbs() {
+			return fmt.Errorf("URL %q is not absolute", u)
+		}
+
+		o.absoluteURL = parsed
+		return nil
+	}
+}
+
+// NewRequest creates an API request. A relative URL can be provided in urlStr, which will be resolved to the
+// BaseURL of the Client. It is always joined to the BaseURL path: leading slashes are dropped, so "/employee" and
+// "employee" both resolve under a base such as https://host/v2/, and a root-relative path can not escape the base
+// path. Repeated slashes in the path are collapsed. An absolute urlStr must have the scheme and host of the BaseURL
+// and no user info, like the URL given to WithAbsoluteURL. If specified, the value pointed to by body is JSON encoded
+// and included in as the request body.
+func (c *Client) NewRequest(method, urlStr string, body interface{}, opts ...RequestOpt) (*http.Request, error) {
+	var buf io.ReadWriter
+	if body != nil {
+		buf = new(bytes.Buffer)
+		err := json.NewEncoder(buf).Encode(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c.NewRequestRaw(method, urlStr, buf, mediaType, opts...)
+}
+
+// collapseSlashes replaces every run of slashes in the escaped URL path p with a single one.
+func collapseSlashes(p string) string {
+	for strings.Contains(p, "//") {
+		p = strings.Replace(p, "//", "/", -1)
+	}
+
+	return p
+}
+
+// checkOrigin returns an error unless the absolute URL u has the scheme and host of the BaseURL and no user info, so
+// the credentials sent with every request do not reach another host or leave TLS.
+func (c *Client) checkOrigin(u *url.URL) error {
+	switch {
+	case !strings.EqualFold(u.Host, c.BaseURL.Host):
+		return fmt.Errorf("URL host %q does not match base URL host %q", u.Host, c.BaseURL.Host)
+	case !strings.EqualFold(u.Scheme, c.BaseURL.Scheme):
+		return fmt.Errorf("URL scheme %q does not match base URL scheme %q", u.Scheme, c.BaseURL.Scheme)
+	case u.User != nil:
+		return fmt.Errorf("URL %q must not carry user info", u.Redacted())
+	}
+
+	return nil
+}
+
+// NewRequestRaw creates an API request like NewRequest, but streams body to the server untouched instead of JSON
+// encoding it. The Content-Type header is set to contentType when it is not empty.
+func (c *Client) NewRequestRaw(method, urlStr string, body io.Reader, contentType string, opts ...RequestOpt) (*http.Request, error) {
+	ro := &requestOptions{header: http.Header{}}
+	for _, opt := range opts {
+		if err := opt(ro); err != nil {
+			return nil, err
+		}
+	}
+
+	u := ro.absoluteURL
+	if u != nil {
+		if err := c.checkOrigin(u); err != nil {
+			return nil, err
+		}
+	} else {
+		// A relative path is always joined to the BaseURL path: a leading slash would make it root-relative,
+		// dropping a base path such as /v2/, and a leading "//" would be read as a host.
+		rel, err := url.Parse(strings.TrimLeft(urlStr, "/"))
+		if err != nil {
+			return nil, err
+		}
+		if rel.IsAbs() {
+			if err := c.checkOrigin(rel); err != nil {
+				return nil, err
+			}
+		}
+
+		u = c.BaseURL.ResolveReference(rel)
+		// Only literal slashes are collapsed, escaped ones (%2F) belong to a path segment.
+		if escaped := u.EscapedPath(); strings.Contains(escaped, "//") {
+			escaped = collapseSlashes(escaped)
+			if u.Path, err = url.PathUnescape(escaped); err != nil {
+				return nil, err
+			}
+			u.RawPath = escaped
+		}
+	}
+
+	if ro.rawJSON != nil {
+		if body != nil {
+			return nil, errors.New("WithRawJSON can not be combined with a request body")
+		}
+		body = bytes.NewReader(ro.rawJSON)
+		contentType = mediaType
+	}
+
+	if ro.gzip && body != nil {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := io.Copy(zw, body); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		// A bytes.Reader keeps the compressed body rewindable for retries.
+		body = bytes.NewReader(buf.Bytes())
+		ro.header.Set("Content-Encoding", "gzip")
+	}
+
+	var signed []byte
+	if c.signer != nil && body != nil {
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		signed = data
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Add("Content-Type", contentType)
+	}
+	req.Header.Add("Accept", mediaType)
+	req.Header.Add("User-Agent", c.UserAgent)
+	if c.acceptLanguage != "" {
+		req.Header.Set("Accept-Language", c.acceptLanguage)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	for k, v := range ro.header {
+		req.Header[k] = v
+	}
+	if c.disableCompression && req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	if req.Header.Get(requestIDHeader) == "" {
+		id, err := newRequestID()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(requestIDHeader, id)
+	}
+	if c.host != "" {
+		req.Host = c.host
+	}
+	req = req.WithContext(context.WithValue(req.Context(), requestOptionsKey{}, ro))
+
+	if c.signer != nil {
+		if err := c.signer(req, signed); err != nil {
+			return nil, err
+		}
+	}
+
+	// out, err := httputil.DumpRequestOut(req, true)
+	// if err != nil {
+	// 	log.Fatal(err)
+	// }
+	// fmt.Println(strings.Replace(string(out), "\r", "", -1))
+	// fmt.Println("--newRequest--")
+
+	return req, nil
+}
+
+// RequestID returns the request ID echoed by the server in the X-Request-ID header of the response, or "" when the
+// server did not echo it.
+func (r *Response) RequestID() string {
+	return r.Header.Get(requestIDHeader)
+}
+
+// Trailers returns the HTTP trailers sent after the response body, such as server-side timings. Trailers are only
+// known once the body has been read to the end, which Do always does before returning, so they are available on
+// any Response returned by Do. The result is nil when the server sent none.
+func (r *Response) Trailers() http.Header {
+	if r.Response == nil || len(r.Trailer) == 0 {
+		return nil
+	}
+
+	return r.Trailer
+}
+
+// Decode decodes the response body retained by Do into v, so a response can be parsed again as another type.
+// The body is only retained when Do read it to decode a value or check its error envelope; ErrBodyNotRetained is
+// returned otherwise.
+func (r *Response) Decode(v interface{}) error {
+	if r.body == nil {
+		return ErrBodyNotRetained
+	}
+
+	return json.Unmarshal(r.body, v)
+}
+
+// ErrBodyNotRetained is returned by Response.Decode when Do did not keep the response body, either because it was
+// called with a nil value or copied the body to an io.Writer.
+var ErrBodyNotRetained = errors.New("response body was not retained")
+
+// newResponse creates a new Response for the provided http.Response
+func newResponse(r *http.Response) *Response {
+	response := Response{Response: r}
+
+	return &response
+}
+
+// Do sends an API request and returns the API response. The API response is JSON decoded and stored in the value
+// pointed to by v, or returned as an error if an API error has occurred. If v implements the io.Writer interface,
+// the raw response will be written to v, without attempting to decode it.
+func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*Response, error) {
+	ro := requestOptionsFrom(req)
+
+	ctx, stop, err := c.cancels.bind(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+
+	var trace *tracer
+	if ro.trace {
+		trace = new(tracer)
+		ctx = httptrace.WithClientTrace(ctx, trace.clientTrace())
+	}
+	req = req.WithContext(ctx)
+
+	if c.dryRun != nil {
+		resp, err := c.dryRun.capture(req)
+		if err != nil {
+			return nil, err
+		}
+		return newResponse(resp), nil
+	}
+
+	// status is the final status of the write, 0 when it got no response.
+	var status int
+	if c.dedup != nil {
+		if key, ok := writeKey(req); ok {
+			if !c.dedup.start(key, c.clock.Now()) {
+				return nil, ErrDuplicateRequest
+			}
+			defer func() { c.dedup.finish(key, status, c.clock.Now()) }()
+		}
+	}
+
+	var attempts int
+	resp, err := c.authorizedRoundTrip(ctx, req, ro, &attempts)
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rerr := resp.Body.Close(); err == nil {
+			err = rerr
+		}
+	}()
+
+	response := newResponse(resp)
+	response.Attempts = attempts
+	if trace != nil {
+		response.Trace = trace.result()
+	}
+
+	// outResp, err := httputil.DumpResponse(resp, true)
+	// if err != nil {
+	// 	log.Fatal(err)
+	// }
+	// fmt.Println(strings.Replace(string(outResp), "\r", "", -1))
+	// fmt.Println("-Do---")
+
+	check := !ro.isExpected(resp.StatusCode)
+
+	// A HEAD response never has a body, whatever its Content-Length says; there is nothing to decode.
+	if req.Method == http.MethodHead {
+		v = nil
+	}
+	if c.disableCompression && req.Method != http.MethodHead {
+		if err := decompress(resp); err != nil {
+			return response, err
+		}
+	}
+
+	// A successful body copied to an io.Writer is streamed; any other body is read once and shared by the error
+	// check and the decoding.
+	src := c.tap(resp.Body)
+	if w, ok := v.(io.Writer); ok && (!check || success(resp.StatusCode)) {
+		if ro.progress != nil {
+			w = &progressWriter{w: w, fn: ro.progress, total: resp.ContentLength}
+		}
+		_, err := io.Copy(w, src)
+		if err != nil {
+			return nil, err
+		}
+		return response, err
+	}
+
+	body := &errReader{r: src}
+	data, err := ioutil.ReadAll(body)
+	if check && !success(resp.StatusCode) {
+		if err != nil {
+			// What was read of a broken error body is not worth parsing.
+			data = nil
+		}
+		return response, checkResponseData(resp, data)
+	}
+	if err != nil {
+		return response, decodeErr(body, err)
+	}
+
+	if c.checkErrorEnvelope {
+		response.body = data
+		if err := checkErrorEnvelope(resp, data); err != nil {
+			return response, err
+		}
+	}
+	if v != nil {
+		response.body = data
+		if err := c.decode(data, v); err != nil {
+			return response, decodeErr(body, err)
+		}
+		// Service methods decoding an intermediate value check the resources they return themselves, so an empty
+		// list envelope is not mistaken for an empty resource.
+		if c.rejectEmpty && !ro.intermediate && emptyResource(data, v) {
+			return response, ErrEmptyResource
+		}
+		if !ro.intermediate {
+			if err := c.postProcess(v, response); err != nil {
+				return response, err
+			}
+		}
+	}
+
+	return response, err
+}
+
+// checkErrorEnvelope returns an *ErrorResponse when data, the body of a successful response, holds a non-empty
+// directory error envelope.
+func checkErrorEnvelope(r *http.Response, data []byte) error {
+	var envelope struct {
+		Error *CustomError `json:"error"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Error == nil {
+		return nil
+	}
+
+	e := envelope.Error
+	if e.Code == 0 && e.Message == "" && len(e.Errors) == 0 {
+		return nil
+	}
+
+	return &ErrorResponse{Response: r, CustomError: *e}
+}
+
+// ErrEmptyResource is returned, when enabled with SetRejectEmptyResources, for a successful response holding an
+// empty JSON object.
+var ErrEmptyResource = errors.New("response is an empty resource")
+
+// emptyResource reports whether data is an empty JSON object that left the struct pointed to by v with only zero
+// fields.
+func emptyResource(data []byte, v interface{}) bool {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil || object == nil || len(object) > 0 {
+		return false
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return false
+	}
+
+	return rv.Elem().IsZero()
+}
+
+// decode decodes the JSON value in data into v. An empty body leaves v untouched.
+func (c *Client) decode(data []byte, v interface{}) error {
+	if c.decoder != nil {
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil
+		}
+		if err := c.decoder(bytes.NewReader(data), v); err != nil {
+			return err
+		}
+		if c.strictDecoding {
+			return checkUnknownFields(data, reflect.TypeOf(v))
+		}
+		return nil
+	}
+
+	if !c.strictDecoding {
+		err := json.NewDecoder(bytes.NewReader(data)).Decode(v)
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		// Unmarshal reports a cut-short value as a syntax error, unlike Decoder.
+		if json.NewDecoder(bytes.NewReader(data)).Decode(new(json.RawMessage)) == io.ErrUnexpectedEOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	return checkUnknownFields(data, reflect.TypeOf(v))
+}
+
+// ErrTruncatedResponse is returned by Do when the response body ends, or the connection breaks, before the JSON
+// value is complete. An empty body is not an error.
+var ErrTruncatedResponse = errors.New("truncated response body")
+
+// errReader records the first error other than io.EOF returned by r, so a broken connection can be told apart from
+// a malformed body.
+type errReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+
+	return n, err
+}
+
+// decodeErr returns the error to report for a failed decode of a body read through r.
+func decodeErr(r *errReader, err error) error {
+	if r.err != nil || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: %v", ErrTruncatedResponse, err)
+	}
+
+	return err
+}
+
+// send sends req once through the circuit breaker.
+func (c *Client) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if c.breaker != nil {
+		if err := c.breaker.allow(c.clock.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if c.breaker != nil {
+		// Only transport errors and server errors count against the backend; a cancelled caller does not.
+		if err != nil && ctx.Err() != nil {
+			c.breaker.release()
+		} else {
+			c.breaker.record(err == nil && resp.StatusCode < http.StatusInternalServerError, c.clock.Now())
+		}
+	}
+
+	return resp, err
+}
+
+// progressWriter reports the bytes written through it to a ProgressFunc.
+type progressWriter struct {
+	w     io.Writer
+	fn    ProgressFunc
+	read  int64
+	total int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.read += int64(n)
+	p.fn(p.read, p.total)
+
+	return n, err
+}
+
+// Close releases the idle connections kept by the client's transport. It is a no-op for transports that do not
+// pool connections.
+func (c *Client) Close() {
+	c.client.CloseIdleConnections()
+}
+
+// Ping sends a GET request to the BaseURL to check that the directory API is reachable.
+func (c *Client) Ping(ctx context.Context) (*Response, error) {
+	req, err := c.NewRequest("GET", "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Do(ctx, req, nil)
+}
+
+// GetInto issues a GET request for path, with the query parameters encoded from opt, and decodes the response into
+// v. It is the escape hatch for endpoints that have no typed wrapper; opt may be nil.
+func (c *Client) GetInto(ctx context.Context, path string, opt interface{}, v interface{}) (*Response, error) {
+	if opt != nil {
+		var err error
+		path, err = addOptions(path, opt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := c.NewRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Do(ctx, req, v)
+}
+
+// GetRawValidated issues a GET request for path and decodes the response into v strictly, for contract tests: an
+// unknown field or a field of the wrong type fails with an error naming the field, whatever the decoding settings
+// of the client.
+func (c *Client) GetRawValidated(ctx context.Context, path string, v interface{}) (*Response, error) {
+	req, err := c.NewRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	resp, err := c.Do(ctx, req, &buf)
+	if err != nil {
+		return resp, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		if terr, ok := err.(*json.UnmarshalTypeError); ok {
+			return resp, fmt.Errorf("GET %v: field %q: expected %v, got JSON %v", path, terr.Field, terr.Type, terr.Value)
+		}
+		return resp, fmt.Errorf("GET %v: %v", path, err)
+	}
+
+	// Types with their own UnmarshalJSON, like User, are not covered by DisallowUnknownFields.
+	if err := checkUnknownFields(buf.Bytes(), reflect.TypeOf(v)); err != nil {
+		return resp, fmt.Errorf("GET %v: %v", path, err)
+	}
+
+	return resp, nil
+}
+
+// Head issues a HEAD request for path, with the query parameters encoded from opt, and returns the response
+// headers without transferring a body. It is meant for existence checks and cache validation; opt may be nil.
+func (c *Client) Head(ctx context.Context, path string, opt interface{}) (*Response, error) {
+	if opt != nil {
+		var err error
+		path, err = addOptions(path, opt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := c.NewRequest(http.MethodHead, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Do(ctx, req, nil)
+}
+
+// Options issues an OPTIONS request for urlStr and returns the methods listed in the Allow header of the response.
+func (c *Client) Options(ctx context.Context, urlStr string) ([]string, *Response, error) {
+	req, err := c.NewRequest("OPTIONS", urlStr, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := c.Do(ctx, req, nil)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var methods []string
+	for _, v := range resp.Header.Values("Allow") {
+		for _, m := range strings.Split(v, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				methods = append(methods, strings.ToUpper(m))
+			}
+		}
+	}
+
+	return methods, resp, nil
+}
+
+// Validate checks that the client is configured with an absolute BaseURL and then pings the directory API.
+// A *ConfigError is returned for a bad base URL or rejected credentials, and a *ConnectionError when the host can
+// not be reached.
+func (c *Client) Validate(ctx context.Context) error {
+	if c.BaseURL == nil {
+		return &ConfigError{Message: "base URL is not set"}
+	}
+	if !c.BaseURL.IsAbs() || c.BaseURL.Host == "" {
+		return &ConfigError{Message: fmt.Sprintf("base URL %q is not absolute", c.BaseURL)}
+	}
+
+	_, err := c.Ping(ctx)
+	if err == nil {
+		return nil
+	}
+
+	if errResp, ok := err.(*ErrorResponse); ok {
+		switch errResp.Response.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return &ConfigError{Message: fmt.Sprintf("credentials rejected: %v", errResp.Response.Status)}
+		}
+
+		// Any other API response means the host is reachable.
+		return nil
+	}
+
+	return &ConnectionError{Err: err}
+}
+
+// CheckResponse checks the API response for errors, and returns them if present. A response is considered an
+// error if its status code is outside the success range 200 <= code < 300, except for a 304 Not Modified answering
+// a conditional request (one sent with If-None-Match or If-Modified-Since), which means the cached copy of the
+// caller is still current. API error responses are expected to have either no response body, or a JSON response
+// body that maps to ErrorResponse. Any other response body will be silently ignored.
+func CheckResponse(r *http.Response) error {
+	if success(r.StatusCode) || notModified(r) {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		data = nil
+	}
+
+	return checkResponseData(r, data)
+}
+
+// checkResponseData is CheckResponse for a response whose body has already been read into data.
+func checkResponseData(r *http.Response, data []byte) error {
+	if success(r.StatusCode) || notModified(r) {
+		return nil
+	}
+
+	errorResponse := &ErrorResponse{Response: r}
+	if len(data) > 0 {
+		err := json.Unmarshal(data, errorResponse)
+		if err != nil {
+			return err
+		}
+	}
+
+	return errorResponse
+}
+
+// success reports whether code is in the 200 range.
+func success(code int) bool {
+	return code >= 200 && code < 300
+}
+
+// notModified reports whether r is a 304 Not Modified answering a conditional request.
+func notModified(r *http.Response) bool {
+	if r.StatusCode != http.StatusNotModified || r.Request == nil {
+		return false
+	}
+
+	return r.Request.Header.Get("If-None-Match") != "" || r.Request.Header.Get("If-Modified-Since") != ""
 }
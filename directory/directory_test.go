@@ -1,16 +1,25 @@
 package directory
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -82,7 +91,7 @@ func TestNewClient(t *testing.T) {
 }
 
 func TestNew(t *testing.T) {
-	c, err := New(SetBaseURL("htt://localhost/"))
+	c, err := New(SetBaseURL("http://localhost/"))
 
 	if err != nil {
 		t.Fatalf("New(): %v", err)
@@ -90,6 +99,16 @@ func TestNew(t *testing.T) {
 	testClientDefaults(t, c)
 }
 
+func TestNew_noBaseURL(t *testing.T) {
+	c, err := New()
+	if !errors.Is(err, ErrNoBaseURL) {
+		t.Errorf("New() error = %v, expected %v", err, ErrNoBaseURL)
+	}
+	if c != nil {
+		t.Errorf("New() returned client %+v, expected nil", c)
+	}
+}
+
 func TestNewRequest_badURL(t *testing.T) {
 	c, err := New(SetBaseURL("http://localhost/"))
 	_, err = c.NewRequest("GET", ":", nil)
@@ -161,6 +180,43 @@ func TestDo_redirectLoop(t *testing.T) {
 	}
 }
 
+func TestSetMaxRedirects(t *testing.T) {
+	setup()
+	defer teardown()
+
+	hops := 0
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		hops++
+		w.Header().Set("Location", "/")
+		w.WriteHeader(http.StatusFound)
+	})
+
+	for _, max := range []int{0, 3} {
+		hops = 0
+		SetMaxRedirects(max)(client)
+
+		req, _ := client.NewRequest("GET", "/", nil)
+		_, err := client.Do(ctx, req, nil)
+		if !errors.Is(err, ErrTooManyRedirects) {
+			t.Errorf("Do() with SetMaxRedirects(%d) error = %v, expected %v", max, err, ErrTooManyRedirects)
+		}
+		if hops != max+1 {
+			t.Errorf("Do() with SetMaxRedirects(%d) sent %d requests, expected %d", max, hops, max+1)
+		}
+	}
+
+	hops = 0
+	SetMaxRedirects(-1)(client)
+	req, _ := client.NewRequest("GET", "/", nil)
+	resp, err := client.Do(ctx, req, nil)
+	if _, ok := err.(*ErrorResponse); !ok || resp.StatusCode != http.StatusFound {
+		t.Errorf("Do() with redirects disabled returned %v, expected the 302 as an *ErrorResponse", err)
+	}
+	if hops != 1 {
+		t.Errorf("Do() with redirects disabled sent %d requests, expected 1", hops)
+	}
+}
+
 func TestDo_noContent(t *testing.T) {
 	setup()
 	defer teardown()
@@ -201,6 +257,41 @@ func TestCheckResponse(t *testing.T) {
 	}
 }
 
+func TestCheckResponse_statusBoundaries(t *testing.T) {
+	conditional := http.Header{"If-None-Match": {`"v1"`}}
+
+	tests := []struct {
+		code    int
+		header  http.Header
+		success bool
+	}{
+		{code: 199},
+		{code: 200, success: true},
+		{code: 299, success: true},
+		{code: 300},
+		{code: 304},
+		{code: 304, header: conditional, success: true},
+		{code: 200, header: conditional, success: true},
+	}
+
+	for _, tt := range tests {
+		res := &http.Response{
+			Request:    &http.Request{Header: tt.header},
+			StatusCode: tt.code,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}
+		err := CheckResponse(res)
+		if tt.success && err != nil {
+			t.Errorf("CheckResponse(%d, %v) returned %v, expected nil", tt.code, tt.header, err)
+		}
+		if !tt.success {
+			if _, ok := err.(*ErrorResponse); !ok {
+				t.Errorf("CheckResponse(%d, %v) returned %v, expected an *ErrorResponse", tt.code, tt.header, err)
+			}
+		}
+	}
+}
+
 // ensure that we properly handle API errors that do not contain a response
 // body
 func TestCheckResponse_noBody(t *testing.T) {
@@ -235,7 +326,7 @@ func TestNewRequest(t *testing.T) {
 	// test that body was JSON encoded
 	body, _ := ioutil.ReadAll(req.Body)
 	if got, want := string(body), outBody; got != want {
-		t.Errorf("NewRequest(%q) Body is -%v-, want %v", inBody, got, want)
+		t.Errorf("NewRequest(%+v) Body is -%v-, want %v", inBody, got, want)
 	}
 
 	// test that relative URL was expanded
@@ -262,6 +353,24 @@ func TestNewRequest_withCustomUserAgent(t *testing.T) {
 		t.Errorf("New() UserAgent = %s; expected %s", got, expected)
 	}
 }
+
+func TestNewRequest_withExactUserAgent(t *testing.T) {
+	ua := "badge-service/2.1"
+	c, err := New(SetUserAgent("ignored"), SetExactUserAgent(ua), SetBaseURL("http://localhost/"))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	req, err := c.NewRequest("GET", "/foo", nil)
+	if err != nil {
+		t.Fatalf("NewRequest() unexpected error: %v", err)
+	}
+
+	if got := req.Header.Get("User-Agent"); got != ua {
+		t.Errorf("New() UserAgent = %s; expected %s", got, ua)
+	}
+}
+
 func TestNewRequest_withBaseURL(t *testing.T) {
 
 	base := "http://localhost/foo"
@@ -287,6 +396,15 @@ func TestNewRequest_withBaseURL_badURL(t *testing.T) {
 	testURLParseError(t, err)
 }
 
+func TestSetBaseURL_invalid(t *testing.T) {
+	for _, base := range []string{"localhost:8080", "htt://localhost/", "http:///employee", "/v2/"} {
+		_, err := New(SetBaseURL(base))
+		if _, ok := err.(*ConfigError); !ok {
+			t.Errorf("New(SetBaseURL(%q)) returned %v, expected a *ConfigError", base, err)
+		}
+	}
+}
+
 func TestErrorResponse_error(t *testing.T) {
 	res := &http.Response{Request: &http.Request{}}
 	customError := CustomError{Code: 0, Message: "m"}
@@ -309,3 +427,1194 @@ func TestNewRequest_emptyBody(t *testing.T) {
 		t.Fatalf("constructed request contains a non-nil Body")
 	}
 }
+
+func TestValidate_noBaseURL(t *testing.T) {
+	c := NewClient()
+
+	err := c.Validate(ctx)
+	if _, ok := err.(*ConfigError); !ok {
+		t.Errorf("Validate() expected *ConfigError, got %#v", err)
+	}
+}
+
+func TestValidate_unreachableHost(t *testing.T) {
+	s := httptest.NewServer(http.NotFoundHandler())
+	s.Close()
+
+	c, _ := New(SetBaseURL(s.URL))
+
+	err := c.Validate(ctx)
+	if _, ok := err.(*ConnectionError); !ok {
+		t.Errorf("Validate() expected *ConnectionError, got %#v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+	})
+
+	if err := client.Validate(ctx); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+}
+
+func TestValidate_unauthorized(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	err := client.Validate(ctx)
+	if _, ok := err.(*ConfigError); !ok {
+		t.Errorf("Validate() expected *ConfigError, got %#v", err)
+	}
+}
+
+func TestSetHTTP2(t *testing.T) {
+	c, err := New(SetBaseURL("http://localhost/"), SetHTTP2(true))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	tr, ok := c.client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("SetHTTP2() transport = %T, expected *http.Transport", c.client.Transport)
+	}
+	if !tr.ForceAttemptHTTP2 {
+		t.Errorf("SetHTTP2(true) ForceAttemptHTTP2 = false, expected true")
+	}
+	if c.client == http.DefaultClient || tr == http.DefaultTransport {
+		t.Errorf("SetHTTP2() modified the default HTTP client")
+	}
+
+	c, _ = New(SetBaseURL("http://localhost/"), SetHTTP2(false))
+	tr = c.client.Transport.(*http.Transport)
+	if tr.ForceAttemptHTTP2 || tr.TLSNextProto == nil {
+		t.Errorf("SetHTTP2(false) did not disable HTTP/2")
+	}
+}
+
+func TestSetHTTP2_customTransport(t *testing.T) {
+	hc := &http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}
+
+	_, err := New(SetBaseURL("http://localhost/"), SetHTTPClient(hc), SetHTTP2(true))
+	if err == nil {
+		t.Errorf("SetHTTP2() expected error for a non *http.Transport")
+	}
+}
+
+func TestSetDisableCompression(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept-Encoding"); got != "gzip" {
+			t.Errorf("Accept-Encoding = %q, expected gzip", got)
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		fmt.Fprint(zw, `{"id":"erick"}`)
+		zw.Close()
+	})
+
+	if err := SetDisableCompression(true)(client); err != nil {
+		t.Fatalf("SetDisableCompression() returned error: %v", err)
+	}
+	if tr := client.client.Transport.(*http.Transport); !tr.DisableCompression {
+		t.Errorf("Transport.DisableCompression = false, expected true")
+	}
+	if http.DefaultTransport.(*http.Transport).DisableCompression {
+		t.Errorf("SetDisableCompression() modified http.DefaultTransport")
+	}
+
+	// The body is gzip encoded once; decompressing it twice would fail to decode.
+	user, resp, err := client.Users.Get(ctx, "erick", nil)
+	if err != nil {
+		t.Fatalf("Users.Get() returned error: %v", err)
+	}
+	if user.ID != "erick" {
+		t.Errorf("Users.Get() returned %+v, expected erick", user)
+	}
+	if got := resp.Header.Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, expected it removed once decompressed", got)
+	}
+}
+
+func TestSetResponseHeaderTimeout(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	mux.HandleFunc("/trickle", func(w http.ResponseWriter, r *http.Request) {
+		// The headers come right away; the body takes longer than the header timeout.
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, `{"id":"erick"}`)
+	})
+
+	if err := SetResponseHeaderTimeout(20 * time.Millisecond)(client); err != nil {
+		t.Fatalf("SetResponseHeaderTimeout() returned error: %v", err)
+	}
+	if got := client.client.Transport.(*http.Transport).ResponseHeaderTimeout; got != 20*time.Millisecond {
+		t.Errorf("ResponseHeaderTimeout = %v, expected %v", got, 20*time.Millisecond)
+	}
+
+	req, _ := client.NewRequest("GET", "slow", nil)
+	_, err := client.Do(ctx, req, nil)
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() || !strings.Contains(err.Error(), "awaiting response headers") {
+		t.Errorf("Do() error = %v, expected a response header timeout", err)
+	}
+
+	req, _ = client.NewRequest("GET", "trickle", nil)
+	user := new(User)
+	if _, err := client.Do(ctx, req, user); err != nil || user.ID != "erick" {
+		t.Errorf("Do() = %+v, %v, expected the slow body to be read", user, err)
+	}
+}
+
+func TestSetHTTPClient_keepsTransportSettings(t *testing.T) {
+	opts := []ClientOpt{
+		SetResponseHeaderTimeout(2 * time.Second),
+		SetMaxResponseHeaderBytes(64 << 10),
+		SetHTTP2(false),
+		SetDisableCompression(true),
+	}
+	hc := &http.Client{Transport: &http.Transport{}}
+
+	orders := map[string][]ClientOpt{
+		"before": append([]ClientOpt{SetBaseURL("http://localhost/"), SetHTTPClient(hc)}, opts...),
+		"after":  append(append([]ClientOpt{SetBaseURL("http://localhost/")}, opts...), SetHTTPClient(hc)),
+	}
+	for name, order := range orders {
+		c, err := New(order...)
+		if err != nil {
+			t.Fatalf("New() with SetHTTPClient %v returned error: %v", name, err)
+		}
+
+		tr := c.client.Transport.(*http.Transport)
+		if tr.ResponseHeaderTimeout != 2*time.Second || tr.MaxResponseHeaderBytes != 64<<10 ||
+			tr.TLSNextProto == nil || !tr.DisableCompression {
+			t.Errorf("SetHTTPClient %v the transport options: transport = %+v", name, tr)
+		}
+		if got := c.Config().ResponseHeaderTimeout; got != 2*time.Second {
+			t.Errorf("Config().ResponseHeaderTimeout with SetHTTPClient %v = %v, expected %v", name, got, 2*time.Second)
+		}
+	}
+
+	if hc.Transport.(*http.Transport).ResponseHeaderTimeout != 0 {
+		t.Errorf("SetHTTPClient() modified the caller supplied transport")
+	}
+}
+
+func TestSetMaxResponseHeaderBytes(t *testing.T) {
+	c, err := New(SetBaseURL("http://localhost/"), SetMaxResponseHeaderBytes(64<<10))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if got := c.client.Transport.(*http.Transport).MaxResponseHeaderBytes; got != 64<<10 {
+		t.Errorf("MaxResponseHeaderBytes = %d, expected %d", got, 64<<10)
+	}
+	if http.DefaultTransport.(*http.Transport).MaxResponseHeaderBytes != 0 {
+		t.Errorf("SetMaxResponseHeaderBytes() modified http.DefaultTransport")
+	}
+
+	if _, err := New(SetBaseURL("http://localhost/"), SetMaxResponseHeaderBytes(-1)); err == nil {
+		t.Errorf("SetMaxResponseHeaderBytes(-1) expected error")
+	}
+}
+
+func TestSetMaxResponseHeaderBytes_exceeded(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Padding", strings.Repeat("x", 4096))
+	})
+
+	SetMaxResponseHeaderBytes(1024)(client)
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	if _, err := client.Do(ctx, req, nil); err == nil {
+		t.Errorf("Do() expected error for headers over the limit")
+	}
+}
+
+func TestDo_protocol(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	resp, err := client.Do(ctx, req, nil)
+	if err != nil {
+		t.Fatalf("Do(): %v", err)
+	}
+
+	if got, want := resp.Proto, "HTTP/1.1"; got != want {
+		t.Errorf("Response.Proto = %q, expected %q", got, want)
+	}
+	if resp.IsHTTP2() {
+		t.Errorf("Response.IsHTTP2() = true, expected false")
+	}
+}
+
+func TestDo_protocolHTTP2(t *testing.T) {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	defer s.Close()
+
+	c, err := New(SetBaseURL(s.URL), SetHTTPClient(s.Client()), SetHTTP2(true))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	req, _ := c.NewRequest("GET", "/", nil)
+	resp, err := c.Do(ctx, req, nil)
+	if err != nil {
+		t.Fatalf("Do(): %v", err)
+	}
+
+	if !resp.IsHTTP2() {
+		t.Errorf("Response.Proto = %q, expected HTTP/2", resp.Proto)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestNewRequestRaw(t *testing.T) {
+	setup()
+	defer teardown()
+
+	payload := []byte("id,fullName\nerick,Erick Guevara\n\x00\xff")
+
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		if got, want := r.Header.Get("Content-Type"), "text/csv"; got != want {
+			t.Errorf("Content-Type = %q, expected %q", got, want)
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if !bytes.Equal(body, payload) {
+			t.Errorf("Request body = %q, expected %q", body, payload)
+		}
+	})
+
+	req, err := client.NewRequestRaw("POST", "upload", bytes.NewReader(payload), "text/csv")
+	if err != nil {
+		t.Fatalf("NewRequestRaw() unexpected error: %v", err)
+	}
+
+	if _, err := client.Do(ctx, req, nil); err != nil {
+		t.Fatalf("Do(): %v", err)
+	}
+}
+
+func TestErrorResponse_FieldErrors(t *testing.T) {
+	res := &http.Response{
+		Request:    &http.Request{},
+		StatusCode: http.StatusBadRequest,
+		Body: ioutil.NopCloser(strings.NewReader(`{"error": {
+			"code": 400,
+			"message": "Invalid employee.",
+			"errors": [
+				{"domain": "global", "reason": "invalid", "field": "fullName", "message": "fullName is required."},
+				{"domain": "global", "reason": "invalid", "field": "coreId", "message": "coreId is malformed."},
+				{"domain": "global", "reason": "badRequest", "message": "Invalid employee."}
+			]}}`)),
+	}
+	err := CheckResponse(res).(*ErrorResponse)
+
+	expected := map[string]string{
+		"fullName":   "fullName is required.",
+		"coreId":     "coreId is malformed.",
+		"badRequest": "Invalid employee.",
+	}
+	if got := err.FieldErrors(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("FieldErrors() = %v, expected %v", got, expected)
+	}
+}
+
+func TestSetAcceptLanguage_invalid(t *testing.T) {
+	for _, lang := range []string{"", "en_US", "en-", "toolongprimary-US"} {
+		if _, err := New(SetBaseURL("http://localhost/"), SetAcceptLanguage(lang)); err == nil {
+			t.Errorf("SetAcceptLanguage(%q) expected error", lang)
+		}
+	}
+
+	c, _ := New(SetBaseURL("http://localhost/"))
+	if _, err := c.NewRequest("GET", "/", nil, WithAcceptLanguage("en US")); err == nil {
+		t.Errorf("WithAcceptLanguage() expected error for an invalid tag")
+	}
+}
+
+func TestSetTimeout(t *testing.T) {
+	c, _ := New(SetBaseURL("http://localhost/"), SetTimeout(time.Second))
+
+	if got, want := c.client.Timeout, time.Second; got != want {
+		t.Errorf("SetTimeout() Timeout = %v, expected %v", got, want)
+	}
+	if http.DefaultClient.Timeout != 0 {
+		t.Errorf("SetTimeout() modified http.DefaultClient")
+	}
+}
+
+func TestSetTimeout_withHTTPClient(t *testing.T) {
+	hc := &http.Client{Timeout: time.Minute}
+
+	for _, opts := range [][]ClientOpt{
+		{SetTimeout(time.Second), SetHTTPClient(hc)},
+		{SetHTTPClient(hc), SetTimeout(time.Second)},
+	} {
+		c, err := New(append([]ClientOpt{SetBaseURL("http://localhost/")}, opts...)...)
+		if err != nil {
+			t.Fatalf("New() returned error: %v", err)
+		}
+		if got, want := c.client.Timeout, time.Second; got != want {
+			t.Errorf("Timeout = %v, expected %v", got, want)
+		}
+	}
+
+	if hc.Timeout != time.Minute {
+		t.Errorf("SetTimeout() modified the HTTP client given to SetHTTPClient")
+	}
+}
+
+func TestDo_progress(t *testing.T) {
+	setup()
+	defer teardown()
+
+	payload := bytes.Repeat([]byte("0123456789"), 10000)
+	mux.HandleFunc("/photo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(len(payload)))
+		w.Write(payload)
+	})
+
+	var reads, last int64
+	var totals []int64
+	progress := func(bytesRead, total int64) {
+		reads += bytesRead - last
+		last = bytesRead
+		totals = append(totals, total)
+	}
+
+	req, _ := client.NewRequest("GET", "photo", nil, WithProgress(progress))
+	var buf bytes.Buffer
+	if _, err := client.Do(ctx, req, &buf); err != nil {
+		t.Fatalf("Do(): %v", err)
+	}
+
+	if got, want := reads, int64(len(payload)); got != want {
+		t.Errorf("WithProgress() reported %d bytes, expected %d", got, want)
+	}
+	for _, total := range totals {
+		if total != int64(len(payload)) {
+			t.Errorf("WithProgress() total = %d, expected %d", total, len(payload))
+			break
+		}
+	}
+	if !bytes.Equal(buf.Bytes(), payload) {
+		t.Errorf("Do() wrote %d bytes, expected %d", buf.Len(), len(payload))
+	}
+}
+
+type closeIdleTransport struct {
+	http.RoundTripper
+	closed int
+}
+
+func (t *closeIdleTransport) CloseIdleConnections() {
+	t.closed++
+}
+
+func TestClose(t *testing.T) {
+	// Default client.
+	NewClient().Close()
+
+	// Transport without CloseIdleConnections.
+	c, _ := New(SetBaseURL("http://localhost/"), SetHTTPClient(&http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}))
+	c.Close()
+
+	tr := &closeIdleTransport{RoundTripper: http.DefaultTransport}
+	c, _ = New(SetBaseURL("http://localhost/"), SetHTTPClient(&http.Client{Transport: tr}))
+	c.Close()
+	if tr.closed != 1 {
+		t.Errorf("Close() called CloseIdleConnections %d times, expected 1", tr.closed)
+	}
+}
+
+func TestResponse_NextPageURL(t *testing.T) {
+	tests := []struct {
+		links    []string
+		expected string
+	}{
+		{nil, ""},
+		{[]string{`<http://localhost/employee?page=1>; rel="prev"`}, ""},
+		{
+			[]string{`<http://localhost/employee?page=1>; rel="prev", <http://localhost/employee?page=3>; rel="next"`},
+			"http://localhost/employee?page=3",
+		},
+		{
+			[]string{`<http://localhost/employee?page=1>; rel="first"`, `<http://localhost/employee?page=2>; rel=next`},
+			"http://localhost/employee?page=2",
+		},
+		{[]string{`<http://localhost/employee?page=9>; title="x"; rel="next last"`}, "http://localhost/employee?page=9"},
+		{[]string{`broken; rel="next"`}, ""},
+	}
+
+	for _, tt := range tests {
+		r := &Response{Response: &http.Response{Header: http.Header{"Link": tt.links}}}
+		if got := r.NextPageURL(); got != tt.expected {
+			t.Errorf("NextPageURL() with %q = %q, expected %q", tt.links, got, tt.expected)
+		}
+	}
+
+	if got := (&Response{}).NextPageURL(); got != "" {
+		t.Errorf("NextPageURL() without response = %q, expected empty", got)
+	}
+}
+
+func TestDo_accepted(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/async", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc("/sync", func(w http.ResponseWriter, r *http.Request) {})
+
+	req, _ := client.NewRequest("POST", "async", nil)
+	resp, err := client.Do(ctx, req, nil)
+	if err != nil {
+		t.Fatalf("Do() returned error for 202: %v", err)
+	}
+	if !resp.IsAccepted() || resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Do() 202 response IsAccepted() = false, status %v", resp.StatusCode)
+	}
+
+	req, _ = client.NewRequest("POST", "sync", nil)
+	resp, err = client.Do(ctx, req, nil)
+	if err != nil {
+		t.Fatalf("Do() returned error for 200: %v", err)
+	}
+	if resp.IsAccepted() {
+		t.Errorf("Do() 200 response IsAccepted() = true")
+	}
+}
+
+func TestDo_expectedStatus(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	req, _ := client.NewRequest("GET", "missing", nil, WithExpectedStatus(http.StatusNotFound))
+	resp, err := client.Do(ctx, req, nil)
+	if err != nil {
+		t.Fatalf("Do() returned error for an expected status: %v", err)
+	}
+	if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+		t.Errorf("Do() status = %v, expected %v", got, want)
+	}
+
+	req, _ = client.NewRequest("GET", "missing", nil, WithExpectedStatus(http.StatusConflict))
+	if _, err := client.Do(ctx, req, nil); err == nil {
+		t.Errorf("Do() expected error for an unexpected status")
+	}
+}
+
+func TestNewRequest_withAbsoluteURL(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.BaseURL, _ = url.Parse(server.URL + "/_ah/api/mm/v1/")
+
+	var got string
+	mux.HandleFunc("/_ah/api/mm/v1/employee", func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.RequestURI()
+	})
+
+	next := server.URL + "/_ah/api/mm/v1/employee?page=2"
+	req, err := client.NewRequest("GET", "", nil, WithAbsoluteURL(next))
+	if err != nil {
+		t.Fatalf("NewRequest() unexpected error: %v", err)
+	}
+	if req.URL.String() != next {
+		t.Errorf("NewRequest() URL = %v, expected %v", req.URL, next)
+	}
+
+	if _, err := client.Do(ctx, req, nil); err != nil {
+		t.Fatalf("Do(): %v", err)
+	}
+	if want := "/_ah/api/mm/v1/employee?page=2"; got != want {
+		t.Errorf("Do() requested %v, expected %v", got, want)
+	}
+}
+
+func TestNewRequest_withAbsoluteURL_errors(t *testing.T) {
+	c, _ := New(SetBaseURL("http://localhost/"))
+
+	for _, u := range []string{"http://example.com/employee", "employee?page=2", ":"} {
+		if _, err := c.NewRequest("GET", "", nil, WithAbsoluteURL(u)); err == nil {
+			t.Errorf("NewRequest() with WithAbsoluteURL(%q) expected error", u)
+		}
+	}
+}
+
+func TestNewRequest_withAbsoluteURL_schemeDowngrade(t *testing.T) {
+	c, _ := New(SetBaseURL("https://directory.example.com/v2/"), SetToken("s3cr3t"))
+
+	for _, u := range []string{
+		"http://directory.example.com/v2/employee?page=2",
+		"https://admin:pw@directory.example.com/v2/employee?page=2",
+	} {
+		if req, err := c.NewRequest("GET", "", nil, WithAbsoluteURL(u)); err == nil {
+			t.Errorf("NewRequest() with WithAbsoluteURL(%q) = %v, expected error", u, req.URL)
+		}
+	}
+
+	if _, err := c.NewRequest("GET", "", nil, WithAbsoluteURL("https://directory.example.com/v2/employee?page=2")); err != nil {
+		t.Errorf("NewRequest() with a same scheme and host URL returned error: %v", err)
+	}
+}
+
+func TestNewRequest_absoluteURLStr(t *testing.T) {
+	c, _ := New(SetBaseURL("https://directory.example.com/v2/"), SetToken("s3cr3t"))
+
+	for _, u := range []string{
+		"https://evil.example.com/steal",
+		"http://directory.example.com/v2/employee",
+		"https://admin:pw@directory.example.com/v2/employee",
+	} {
+		if req, err := c.NewRequest("GET", u, nil); err == nil {
+			t.Errorf("NewRequest(%q) = %v, expected error", u, req.URL)
+		}
+	}
+
+	req, err := c.NewRequest("GET", "https://directory.example.com/v2/employee", nil)
+	if err != nil || req.URL.String() != "https://directory.example.com/v2/employee" {
+		t.Errorf("NewRequest() with a same origin URL = %v, %v", req, err)
+	}
+
+	if _, err := c.Head(ctx, "https://evil.example.com/steal", nil); err == nil {
+		t.Errorf("Head() expected error for another host")
+	}
+}
+
+func TestErrorResponse_statusText(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	})
+
+	req, _ := client.NewRequest("GET", "employee/erick", nil)
+	_, err := client.Do(ctx, req, nil)
+	if err == nil {
+		t.Fatalf("Do() expected error")
+	}
+
+	if got, want := err.Error(), "GET /employee/erick: 504 Gateway Timeout"; got != want {
+		t.Errorf("Error() = %q, expected %q", got, want)
+	}
+
+	// Responses built by hand may lack Status.
+	err = &ErrorResponse{Response: &http.Response{StatusCode: http.StatusBadGateway}}
+	if got, want := err.Error(), "502 Bad Gateway"; got != want {
+		t.Errorf("Error() = %q, expected %q", got, want)
+	}
+}
+
+func TestSetSigner(t *testing.T) {
+	setup()
+	defer teardown()
+
+	key := []byte("secret")
+	sign := func(method, path string, body []byte) string {
+		mac := hmac.New(sha256.New, key)
+		fmt.Fprintf(mac, "%s\n%s\n", method, path)
+		mac.Write(body)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if got, want := r.Header.Get("X-Signature"), sign(r.Method, r.URL.Path, body); got != want {
+			t.Errorf("X-Signature = %q, expected %q", got, want)
+		}
+	})
+
+	SetSigner(func(req *http.Request, body []byte) error {
+		req.Header.Set("X-Signature", sign(req.Method, req.URL.Path, body))
+		return nil
+	})(client)
+
+	req, err := client.NewRequest("POST", "employee", &User{ID: "erick"})
+	if err != nil {
+		t.Fatalf("NewRequest() unexpected error: %v", err)
+	}
+	if req.GetBody == nil {
+		t.Errorf("NewRequest() signed body is not rewindable")
+	}
+	if _, err := client.Do(ctx, req, nil); err != nil {
+		t.Fatalf("Do(): %v", err)
+	}
+
+	req, _ = client.NewRequestRaw("PUT", "employee", strings.NewReader("raw"), "text/plain")
+	if _, err := client.Do(ctx, req, nil); err != nil {
+		t.Fatalf("Do(): %v", err)
+	}
+
+	req, _ = client.NewRequest("GET", "employee", nil)
+	if _, err := client.Do(ctx, req, nil); err != nil {
+		t.Fatalf("Do(): %v", err)
+	}
+}
+
+func TestSetSigner_error(t *testing.T) {
+	c, _ := New(SetBaseURL("http://localhost/"), SetSigner(func(*http.Request, []byte) error {
+		return errors.New("no key")
+	}))
+
+	if _, err := c.NewRequest("GET", "/", nil); err == nil {
+		t.Errorf("NewRequest() expected signer error")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "OPTIONS")
+		w.Header().Set("Allow", "GET, post,HEAD")
+		w.Header().Add("Allow", "OPTIONS")
+	})
+
+	methods, _, err := client.Options(ctx, "employee")
+	if err != nil {
+		t.Fatalf("Options() returned error: %v", err)
+	}
+
+	expected := []string{"GET", "POST", "HEAD", "OPTIONS"}
+	if !reflect.DeepEqual(methods, expected) {
+		t.Errorf("Options() returned %+v, expected %+v", methods, expected)
+	}
+}
+
+func TestHead(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "HEAD")
+		testFormValues(t, r, values{"fields": "id"})
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "42")
+	})
+
+	fields := "id"
+	resp, err := client.Head(ctx, "employee/erick", &UsersOptions{Fields: &fields})
+	if err != nil {
+		t.Fatalf("Head() returned error: %v", err)
+	}
+	if etag := resp.Header.Get("ETag"); etag != `"v1"` {
+		t.Errorf("Head() ETag = %q, expected %q", etag, `"v1"`)
+	}
+	if err := resp.Decode(new(User)); err != ErrBodyNotRetained {
+		t.Errorf("Decode() error = %v, expected %v", err, ErrBodyNotRetained)
+	}
+
+	// Do ignores the value of a HEAD request instead of failing to decode the missing body.
+	SetStrictDecoding(true)(client)
+	req, _ := client.NewRequest("HEAD", "employee/erick?fields=id", nil)
+	user := new(User)
+	if _, err := client.Do(ctx, req, user); err != nil {
+		t.Errorf("Do(HEAD) returned error: %v", err)
+	}
+	if !reflect.DeepEqual(user, new(User)) {
+		t.Errorf("Do(HEAD) decoded %+v, expected an untouched User", user)
+	}
+}
+
+func TestDo_truncatedResponse(t *testing.T) {
+	setup()
+	defer teardown()
+
+	type foo struct {
+		A string
+	}
+
+	mux.HandleFunc("/truncated", func(w http.ResponseWriter, r *http.Request) {
+		// The connection is closed after the partial body, short of the announced length.
+		w.Header().Set("Content-Length", "64")
+		fmt.Fprint(w, `{"A":"a`)
+	})
+	mux.HandleFunc("/malformed", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"A":}`)
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, strict := range []bool{false, true} {
+		SetStrictDecoding(strict)(client)
+
+		req, _ := client.NewRequest("GET", "truncated", nil)
+		if _, err := client.Do(ctx, req, new(foo)); !errors.Is(err, ErrTruncatedResponse) {
+			t.Errorf("Do() strict=%v truncated body returned %v, expected %v", strict, err, ErrTruncatedResponse)
+		}
+
+		req, _ = client.NewRequest("GET", "malformed", nil)
+		if _, err := client.Do(ctx, req, new(foo)); err == nil || errors.Is(err, ErrTruncatedResponse) {
+			t.Errorf("Do() strict=%v malformed body returned %v, expected a syntax error", strict, err)
+		}
+
+		req, _ = client.NewRequest("GET", "empty", nil)
+		if _, err := client.Do(ctx, req, new(foo)); err != nil {
+			t.Errorf("Do() strict=%v empty body returned error: %v", strict, err)
+		}
+	}
+}
+
+func TestGetInto(t *testing.T) {
+	setup()
+	defer teardown()
+
+	type office struct {
+		Code string `json:"code"`
+		City string `json:"city"`
+	}
+
+	mux.HandleFunc("/experimental/offices/sfo", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"fields": "code,city"})
+		fmt.Fprint(w, `{"code":"sfo","city":"San Francisco"}`)
+	})
+
+	fields := "code,city"
+	got := new(office)
+	if _, err := client.GetInto(ctx, "experimental/offices/sfo", &UsersOptions{Fields: &fields}, got); err != nil {
+		t.Fatalf("GetInto() returned error: %v", err)
+	}
+
+	expected := &office{Code: "sfo", City: "San Francisco"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("GetInto() decoded %+v, expected %+v", got, expected)
+	}
+}
+
+func TestNewRequest_doubleSlashes(t *testing.T) {
+	tests := []struct {
+		base, path, expected string
+	}{
+		{"http://localhost/", "//employee/erick", "http://localhost/employee/erick"},
+		{"http://localhost/v2/", "employee//erick", "http://localhost/v2/employee/erick"},
+		{"http://localhost//v2/", "employee/erick", "http://localhost/v2/employee/erick"},
+		{"http://localhost/v2/", "employee/erick%2F%2Fx", "http://localhost/v2/employee/erick%2F%2Fx"},
+		{"http://localhost/v2/", "/employee/erick", "http://localhost/v2/employee/erick"},
+		{"http://localhost/v2/", "//employee", "http://localhost/v2/employee"},
+	}
+
+	for _, tt := range tests {
+		c, _ := New(SetBaseURL(tt.base))
+		req, err := c.NewRequest("GET", tt.path, nil)
+		if err != nil {
+			t.Fatalf("NewRequest(%q) returned error: %v", tt.path, err)
+		}
+		if got := req.URL.String(); got != tt.expected {
+			t.Errorf("NewRequest(%q) with base %q URL is %v, expected %v", tt.path, tt.base, got, tt.expected)
+		}
+	}
+}
+
+func TestResponse_Decode(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"kind":"employee","id":"erick","fullName":"Erick Guevara"}`)
+	})
+
+	type envelope struct {
+		Kind string `json:"kind"`
+	}
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	kind := new(envelope)
+	resp, err := client.Do(ctx, req, kind)
+	if err != nil {
+		t.Fatalf("Do() returned error: %v", err)
+	}
+	if kind.Kind != "employee" {
+		t.Errorf("Do() decoded kind %q, expected employee", kind.Kind)
+	}
+
+	user := new(User)
+	if err := resp.Decode(user); err != nil {
+		t.Fatalf("Decode() returned error: %v", err)
+	}
+	if expected := (&User{ID: "erick", FullName: "Erick Guevara"}); !reflect.DeepEqual(user, expected) {
+		t.Errorf("Decode() returned %+v, expected %+v", user, expected)
+	}
+
+	req, _ = client.NewRequest("GET", "/", nil)
+	resp, _ = client.Do(ctx, req, nil)
+	if err := resp.Decode(user); err != ErrBodyNotRetained {
+		t.Errorf("Decode() error = %v, expected %v", err, ErrBodyNotRetained)
+	}
+}
+
+func TestSetDecoder(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"erick"}`)
+	})
+
+	calls := 0
+	SetDecoder(func(r io.Reader, v interface{}) error {
+		calls++
+		return json.NewDecoder(r).Decode(v)
+	})(client)
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	user := new(User)
+	resp, err := client.Do(ctx, req, user)
+	if err != nil {
+		t.Fatalf("Do() returned error: %v", err)
+	}
+	if calls != 1 || user.ID != "erick" {
+		t.Errorf("Do() decoded %+v with %d decoder calls, expected erick with 1", user, calls)
+	}
+
+	// The buffered body stays available.
+	again := new(User)
+	if err := resp.Decode(again); err != nil || again.ID != "erick" {
+		t.Errorf("Decode() = %+v, %v, expected erick", again, err)
+	}
+
+	// Bodies copied to an io.Writer are not decoded.
+	req, _ = client.NewRequest("GET", "/", nil)
+	var buf bytes.Buffer
+	client.Do(ctx, req, &buf)
+	if calls != 1 || buf.String() != `{"id":"erick"}` {
+		t.Errorf("Do() to an io.Writer wrote %q with %d decoder calls", buf.String(), calls)
+	}
+
+	// Service methods decode the user with the same decoder.
+	var users int
+	SetDecoder(func(r io.Reader, v interface{}) error {
+		if _, ok := v.(*User); ok {
+			users++
+		}
+		return json.NewDecoder(r).Decode(v)
+	})(client)
+	got, _, err := client.Users.Get(ctx, "erick", nil)
+	if err != nil {
+		t.Fatalf("Users.Get() returned error: %v", err)
+	}
+	if users != 1 || got.ID != "erick" {
+		t.Errorf("Users.Get() decoded %+v with %d *User decoder calls, expected erick with 1", got, users)
+	}
+}
+
+func TestNewRequest_withGzipBody(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var bodies []string
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Content-Encoding"); got != "gzip" {
+			t.Errorf("Content-Encoding = %q, expected gzip", got)
+		}
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Fatalf("request body is not gzip encoded: %v", err)
+		}
+		body, _ := ioutil.ReadAll(zr)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	SetClock(newFakeClock())(client)
+	SetRetry(1)(client)
+
+	req, _ := client.NewRequest("POST", "employee", &User{ID: "erick"}, WithGzipBody(), WithIdempotencyKey("create-erick"))
+	if _, err := client.Do(ctx, req, nil); err != nil {
+		t.Fatalf("Do() returned error: %v", err)
+	}
+
+	expected := `{"coreId":"","fullName":"","status":"","id":"erick"}` + "\n"
+	if !reflect.DeepEqual(bodies, []string{expected, expected}) {
+		t.Errorf("server received %q, expected the same body on both attempts", bodies)
+	}
+}
+
+func TestSetHost(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Host, "directory.staging.example.com"; got != want {
+			t.Errorf("server received Host %q, expected %q", got, want)
+		}
+	})
+
+	SetHost("directory.staging.example.com")(client)
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	if got, want := req.Host, "directory.staging.example.com"; got != want {
+		t.Errorf("NewRequest() Host = %q, expected %q", got, want)
+	}
+	if got, want := req.URL.Host, client.BaseURL.Host; got != want {
+		t.Errorf("NewRequest() URL host = %q, expected %q", got, want)
+	}
+
+	if _, err := client.Do(ctx, req, nil); err != nil {
+		t.Errorf("Do() returned error: %v", err)
+	}
+}
+
+func TestSetCheckErrorEnvelope(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/failed", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"error":{"code":503,"message":"backend unavailable"}}`)
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"erick","error":{}}`)
+	})
+
+	req, _ := client.NewRequest("GET", "failed", nil)
+	if _, err := client.Do(ctx, req, new(User)); err != nil {
+		t.Errorf("Do() without the check returned error: %v", err)
+	}
+
+	SetCheckErrorEnvelope(true)(client)
+
+	for _, v := range []interface{}{new(User), nil} {
+		req, _ = client.NewRequest("GET", "failed", nil)
+		_, err := client.Do(ctx, req, v)
+		errResp, ok := err.(*ErrorResponse)
+		if !ok {
+			t.Fatalf("Do() returned %v, expected an *ErrorResponse", err)
+		}
+		if errResp.Code != 503 || errResp.Message != "backend unavailable" {
+			t.Errorf("Do() returned %+v, expected the envelope error", errResp.CustomError)
+		}
+	}
+
+	req, _ = client.NewRequest("GET", "ok", nil)
+	user := new(User)
+	if _, err := client.Do(ctx, req, user); err != nil || user.ID != "erick" {
+		t.Errorf("Do() with an empty envelope = %+v, %v, expected erick", user, err)
+	}
+}
+
+func TestDo_bufferedBody(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/bad", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"code":400,"message":"invalid fields"}}`)
+	})
+	mux.HandleFunc("/good", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"erick"}`)
+	})
+
+	req, _ := client.NewRequest("GET", "bad", nil)
+	user := new(User)
+	_, err := client.Do(ctx, req, user)
+	if errResp, ok := err.(*ErrorResponse); !ok || errResp.Message != "invalid fields" {
+		t.Errorf("Do() returned %v, expected the error envelope", err)
+	}
+	if user.ID != "" {
+		t.Errorf("Do() decoded %+v from an error response", user)
+	}
+
+	req, _ = client.NewRequest("GET", "good", nil, WithExpectedStatus(http.StatusOK))
+	resp, err := client.Do(ctx, req, user)
+	if err != nil || user.ID != "erick" {
+		t.Errorf("Do() = %+v, %v, expected erick", user, err)
+	}
+	if again := new(User); resp.Decode(again) != nil || again.ID != "erick" {
+		t.Errorf("Decode() returned %+v, expected erick from the same buffered body", again)
+	}
+
+	// A body copied to an io.Writer is only written on success.
+	req, _ = client.NewRequest("GET", "bad", nil)
+	var buf bytes.Buffer
+	if _, err := client.Do(ctx, req, &buf); err == nil || buf.Len() != 0 {
+		t.Errorf("Do() to an io.Writer = %v, wrote %q, expected the error only", err, buf.String())
+	}
+}
+
+func TestNewRequest_requestID(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var sent []string
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		sent = append(sent, id)
+		w.Header().Set("X-Request-ID", id)
+	})
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	resp, err := client.Do(ctx, req, nil)
+	if err != nil {
+		t.Fatalf("Do() returned error: %v", err)
+	}
+	if len(sent[0]) != 32 {
+		t.Errorf("NewRequest() sent X-Request-ID %q, expected a generated ID", sent[0])
+	}
+	if got := resp.RequestID(); got != sent[0] {
+		t.Errorf("RequestID() = %q, expected the echoed %q", got, sent[0])
+	}
+
+	req, _ = client.NewRequest("GET", "/", nil)
+	client.Do(ctx, req, nil)
+	if sent[1] == sent[0] {
+		t.Errorf("NewRequest() reused the request ID %q", sent[0])
+	}
+
+	req, _ = client.NewRequest("GET", "/", nil, WithRequestID("sync-42"))
+	resp, _ = client.Do(ctx, req, nil)
+	if got := resp.RequestID(); got != "sync-42" {
+		t.Errorf("RequestID() = %q, expected sync-42", got)
+	}
+}
+
+func TestGetRawValidated(t *testing.T) {
+	setup()
+	defer teardown()
+
+	type office struct {
+		Code  string `json:"code"`
+		Floor int    `json:"floor"`
+	}
+
+	mux.HandleFunc("/offices/valid", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"code":"sfo","floor":3}`)
+	})
+	mux.HandleFunc("/offices/extra", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"code":"sfo","floor":3,"city":"San Francisco"}`)
+	})
+	mux.HandleFunc("/offices/wrongType", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"code":"sfo","floor":"third"}`)
+	})
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"erick","title":"Engineer"}`)
+	})
+
+	got := new(office)
+	if _, err := client.GetRawValidated(ctx, "offices/valid", got); err != nil {
+		t.Fatalf("GetRawValidated() returned error: %v", err)
+	}
+	if expected := (&office{Code: "sfo", Floor: 3}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("GetRawValidated() decoded %+v, expected %+v", got, expected)
+	}
+
+	tests := []struct {
+		path     string
+		v        interface{}
+		expected string
+	}{
+		{"offices/extra", new(office), `unknown field "city"`},
+		{"offices/wrongType", new(office), `field "floor": expected int, got JSON string`},
+		{"employee/erick", new(User), `unknown field "title"`},
+	}
+
+	for _, tt := range tests {
+		_, err := client.GetRawValidated(ctx, tt.path, tt.v)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("GetRawValidated(%v) error = %v, expected it to mention %s", tt.path, err, tt.expected)
+		}
+	}
+}
+
+func TestNewRequest_withRawJSON(t *testing.T) {
+	setup()
+	defer teardown()
+
+	payload := []byte(`{"id":"erick",  "fullName":"Erick Guevara"}`)
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		if got := r.Header.Get("Content-Type"); got != mediaType {
+			t.Errorf("Content-Type = %q, expected %q", got, mediaType)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if !bytes.Equal(body, payload) {
+			t.Errorf("server received %s, expected %s", body, payload)
+		}
+	})
+
+	req, err := client.NewRequest("POST", "employee", nil, WithRawJSON(payload))
+	if err != nil {
+		t.Fatalf("NewRequest() returned error: %v", err)
+	}
+	if _, err := client.Do(ctx, req, nil); err != nil {
+		t.Errorf("Do() returned error: %v", err)
+	}
+
+	if _, err := client.NewRequest("POST", "employee", nil, WithRawJSON([]byte(`{"id":`))); err == nil {
+		t.Errorf("NewRequest() expected error for invalid JSON")
+	}
+	if _, err := client.NewRequest("POST", "employee", &User{}, WithRawJSON(payload)); err == nil {
+		t.Errorf("NewRequest() expected error for both a body and raw JSON")
+	}
+}
+
+func TestResponse_Trailers(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "Server-Timing")
+		fmt.Fprint(w, `{"id":"erick"}`)
+		w.Header().Set("Server-Timing", "db;dur=53")
+	})
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	user := new(User)
+	resp, err := client.Do(ctx, req, user)
+	if err != nil {
+		t.Fatalf("Do() returned error: %v", err)
+	}
+	if user.ID != "erick" {
+		t.Errorf("Do() decoded ID %q, expected erick", user.ID)
+	}
+	if got, expected := resp.Trailers().Get("Server-Timing"), "db;dur=53"; got != expected {
+		t.Errorf("Trailers() Server-Timing = %q, expected %q", got, expected)
+	}
+
+	if trailers := (&Response{}).Trailers(); trailers != nil {
+		t.Errorf("Trailers() = %v, expected nil without a response", trailers)
+	}
+}
@@ -0,0 +1,77 @@
+package directory
+
+import (
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// DryRunRequest is a request captured instead of sent while the client is in dry-run mode.
+type DryRunRequest struct {
+	Method string
+	URL    string
+	Body   []byte
+}
+
+// dryRun collects the requests the client would have sent.
+type dryRun struct {
+	mu       sync.Mutex
+	requests []DryRunRequest
+}
+
+// SetDryRun is a client option for building requests without sending them. In dry-run mode Do captures the method,
+// URL and body of every request, returns a synthetic 200 OK response and leaves v untouched. The captured requests
+// are returned by DryRunRequests.
+func SetDryRun(enabled bool) ClientOpt {
+	return func(c *Client) error {
+		if !enabled {
+			c.dryRun = nil
+			return nil
+		}
+		if c.dryRun == nil {
+			c.dryRun = &dryRun{}
+		}
+		return nil
+	}
+}
+
+// DryRunRequests returns the requests captured so far in dry-run mode, in the order Do received them.
+func (c *Client) DryRunRequests() []DryRunRequest {
+	if c.dryRun == nil {
+		return nil
+	}
+
+	c.dryRun.mu.Lock()
+	defer c.dryRun.mu.Unlock()
+
+	return append([]DryRunRequest(nil), c.dryRun.requests...)
+}
+
+// capture records req and returns the synthetic response standing in for the server's.
+func (d *dryRun) capture(req *http.Request) (*http.Response, error) {
+	r := DryRunRequest{Method: req.Method, URL: req.URL.String()}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+
+	d.mu.Lock()
+	d.requests = append(d.requests, r)
+	d.mu.Unlock()
+
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
@@ -0,0 +1,50 @@
+package directory
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestDo_dryRun(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("dry run sent %v %v", r.Method, r.URL)
+	})
+
+	SetDryRun(true)(client)
+
+	req, _ := client.NewRequest("POST", "employee", &User{ID: "erick"})
+	resp, err := client.Do(ctx, req, nil)
+	if err != nil {
+		t.Fatalf("Do() returned error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Do() status = %d, expected %d", resp.StatusCode, http.StatusOK)
+	}
+
+	user, _, err := client.Users.Get(ctx, "erick", nil)
+	if err != nil {
+		t.Fatalf("Users.Get() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(user, &User{}) {
+		t.Errorf("Users.Get() returned %+v, expected an empty User", user)
+	}
+
+	expected := []DryRunRequest{
+		{Method: "POST", URL: server.URL + "/employee", Body: []byte(`{"coreId":"","fullName":"","status":"","id":"erick"}` + "\n")},
+		{Method: "GET", URL: server.URL + "/employee/erick"},
+	}
+	if got := client.DryRunRequests(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("DryRunRequests() returned %+v, expected %+v", got, expected)
+	}
+}
+
+func TestDryRunRequests_disabled(t *testing.T) {
+	c := NewClient()
+	if got := c.DryRunRequests(); got != nil {
+		t.Errorf("DryRunRequests() returned %+v, expected nil", got)
+	}
+}
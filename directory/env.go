@@ -0,0 +1,50 @@
+package directory
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Environment variables read by NewFromEnv.
+const (
+	EnvBaseURL        = "DIRECTORY_BASE_URL"
+	EnvToken          = "DIRECTORY_TOKEN"
+	EnvTimeout        = "DIRECTORY_TIMEOUT"
+	EnvUserAgent      = "DIRECTORY_USER_AGENT"
+	EnvAcceptLanguage = "DIRECTORY_ACCEPT_LANGUAGE"
+)
+
+// NewFromEnv returns a new directory API client configured from the environment. DIRECTORY_BASE_URL is required;
+// DIRECTORY_TOKEN, DIRECTORY_TIMEOUT (a duration such as "5s"), DIRECTORY_USER_AGENT and
+// DIRECTORY_ACCEPT_LANGUAGE are applied when set. The given opts are applied after the environment.
+func NewFromEnv(opts ...ClientOpt) (*Client, error) {
+	baseURL := os.Getenv(EnvBaseURL)
+	if baseURL == "" {
+		return nil, fmt.Errorf("environment variable %v is not set", EnvBaseURL)
+	}
+
+	envOpts := []ClientOpt{SetBaseURL(baseURL)}
+
+	if token := os.Getenv(EnvToken); token != "" {
+		envOpts = append(envOpts, SetToken(token))
+	}
+
+	if timeout := os.Getenv(EnvTimeout); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("environment variable %v: %v", EnvTimeout, err)
+		}
+		envOpts = append(envOpts, SetTimeout(d))
+	}
+
+	if ua := os.Getenv(EnvUserAgent); ua != "" {
+		envOpts = append(envOpts, SetUserAgent(ua))
+	}
+
+	if lang := os.Getenv(EnvAcceptLanguage); lang != "" {
+		envOpts = append(envOpts, SetAcceptLanguage(lang))
+	}
+
+	return New(append(envOpts, opts...)...)
+}
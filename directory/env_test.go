@@ -0,0 +1,57 @@
+package directory
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv(EnvBaseURL, "http://localhost/")
+	t.Setenv(EnvToken, "secret")
+	t.Setenv(EnvTimeout, "3s")
+	t.Setenv(EnvUserAgent, "sync")
+	t.Setenv(EnvAcceptLanguage, "en-US")
+
+	c, err := NewFromEnv()
+	if err != nil {
+		t.Fatalf("NewFromEnv() unexpected error: %v", err)
+	}
+
+	if got, want := c.BaseURL.String(), "http://localhost/"; got != want {
+		t.Errorf("NewFromEnv() BaseURL = %v, expected %v", got, want)
+	}
+	if got, want := c.client.Timeout, 3*time.Second; got != want {
+		t.Errorf("NewFromEnv() Timeout = %v, expected %v", got, want)
+	}
+
+	req, _ := c.NewRequest("GET", "/", nil)
+	if got, want := req.Header.Get("Authorization"), "Bearer secret"; got != want {
+		t.Errorf("NewFromEnv() Authorization = %q, expected %q", got, want)
+	}
+	if got, want := req.Header.Get("User-Agent"), "sync+"+userAgent; got != want {
+		t.Errorf("NewFromEnv() User-Agent = %q, expected %q", got, want)
+	}
+	if got, want := req.Header.Get("Accept-Language"), "en-US"; got != want {
+		t.Errorf("NewFromEnv() Accept-Language = %q, expected %q", got, want)
+	}
+}
+
+func TestNewFromEnv_missingBaseURL(t *testing.T) {
+	t.Setenv(EnvBaseURL, "")
+
+	_, err := NewFromEnv()
+	if err == nil || !strings.Contains(err.Error(), EnvBaseURL) {
+		t.Errorf("NewFromEnv() error = %v, expected it to name %v", err, EnvBaseURL)
+	}
+}
+
+func TestNewFromEnv_badTimeout(t *testing.T) {
+	t.Setenv(EnvBaseURL, "http://localhost/")
+	t.Setenv(EnvTimeout, "soon")
+
+	_, err := NewFromEnv()
+	if err == nil || !strings.Contains(err.Error(), EnvTimeout) {
+		t.Errorf("NewFromEnv() error = %v, expected it to name %v", err, EnvTimeout)
+	}
+}
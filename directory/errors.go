@@ -0,0 +1,105 @@
+package directory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// MultiError collects the errors of a batch call, keyed by the ID each error belongs to. A nil or empty MultiError
+// means no call failed.
+type MultiError map[string]error
+
+func (m MultiError) Error() string {
+	ids := m.ids()
+	msgs := make([]string, len(ids))
+	for i, id := range ids {
+		msgs[i] = fmt.Sprintf("%v: %v", id, m[id])
+	}
+
+	return fmt.Sprintf("%d calls failed: %v", len(m), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the collected errors ordered by ID, so errors.Is and errors.As match any of them.
+func (m MultiError) Unwrap() []error {
+	ids := m.ids()
+	errs := make([]error, len(ids))
+	for i, id := range ids {
+		errs[i] = m[id]
+	}
+
+	return errs
+}
+
+// Any reports whether any call failed.
+func (m MultiError) Any() bool {
+	return len(m) > 0
+}
+
+// All reports whether the call for every one of ids failed.
+func (m MultiError) All(ids []string) bool {
+	for _, id := range ids {
+		if m[id] == nil {
+			return false
+		}
+	}
+
+	return len(ids) > 0
+}
+
+func (m MultiError) ids() []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// IsTemporary reports whether err is worth retrying: a network timeout, a refused or reset connection, a truncated
+// response or an *ErrorResponse for a 429, 500, 502, 503 or 504 status. Client errors (other 4xx), statuses a retry
+// will not change (501, 505), other transport failures such as redirect loops and certificate errors, an open
+// circuit breaker and a cancelled or expired context are not temporary. The retries enabled with SetRetry use the
+// same definition, except for truncated responses, which are only detected after Do stopped retrying.
+func IsTemporary(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrTooManyRedirects) {
+		return false
+	}
+
+	var errResp *ErrorResponse
+	if errors.As(err, &errResp) {
+		if errResp.Response == nil {
+			return false
+		}
+		return temporaryStatus(errResp.Response.StatusCode)
+	}
+
+	if errors.Is(err, ErrTruncatedResponse) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// temporaryStatus reports whether a response with the status code is worth retrying.
+func temporaryStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
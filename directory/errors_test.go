@@ -0,0 +1,100 @@
+package directory
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestMultiError(t *testing.T) {
+	errAPI := errors.New("backend unavailable")
+	m := MultiError{
+		"erick":  fmt.Errorf("get erick: %w", ErrNotFound),
+		"andrew": errAPI,
+	}
+
+	if !errors.Is(m["erick"], ErrNotFound) {
+		t.Errorf("errors.Is(m[erick], ErrNotFound) = false, expected true")
+	}
+	if !errors.Is(m, ErrNotFound) || !errors.Is(m, errAPI) {
+		t.Errorf("errors.Is(m, ...) did not match the wrapped errors")
+	}
+	if errors.Is(m["andrew"], ErrNotFound) {
+		t.Errorf("errors.Is(m[andrew], ErrNotFound) = true, expected false")
+	}
+
+	expected := "2 calls failed: andrew: backend unavailable; erick: get erick: employee not found"
+	if got := m.Error(); got != expected {
+		t.Errorf("Error() = %q, expected %q", got, expected)
+	}
+
+	if !m.Any() {
+		t.Errorf("Any() = false, expected true")
+	}
+	if !m.All([]string{"erick", "andrew"}) {
+		t.Errorf("All() = false, expected true")
+	}
+	if m.All([]string{"erick", "tom"}) {
+		t.Errorf("All() = true with tom succeeding, expected false")
+	}
+
+	var none MultiError
+	if none.Any() || none.All(nil) {
+		t.Errorf("empty MultiError reported failures")
+	}
+}
+
+// timeoutError is a net.Error reporting a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsTemporary(t *testing.T) {
+	status := func(code int) error {
+		return &ErrorResponse{Response: &http.Response{StatusCode: code}}
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"nil", nil, false},
+		{"network timeout", &url.Error{Op: "Get", URL: "http://localhost/", Err: timeoutError{}}, true},
+		{"connection refused", &url.Error{Op: "Get", URL: "http://localhost/", Err: &net.OpError{Op: "dial", Net: "tcp",
+			Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}, true},
+		{"connection reset", &url.Error{Op: "Get", URL: "http://localhost/", Err: &net.OpError{Op: "read", Net: "tcp",
+			Err: os.NewSyscallError("read", syscall.ECONNRESET)}}, true},
+		{"too many redirects", &url.Error{Op: "Get", URL: "http://localhost/", Err: ErrTooManyRedirects}, false},
+		{"certificate", &url.Error{Op: "Get", URL: "https://localhost/", Err: x509.UnknownAuthorityError{}}, false},
+		{"unsupported scheme", &url.Error{Op: "Get", URL: "ftp://localhost/",
+			Err: errors.New(`unsupported protocol scheme "ftp"`)}, false},
+		{"decode error", errors.New("invalid character 'x' looking for beginning of value"), false},
+		{"truncated", fmt.Errorf("%w: unexpected EOF", ErrTruncatedResponse), true},
+		{"500", status(http.StatusInternalServerError), true},
+		{"503", status(http.StatusServiceUnavailable), true},
+		{"429", status(http.StatusTooManyRequests), true},
+		{"501", status(http.StatusNotImplemented), false},
+		{"505", status(http.StatusHTTPVersionNotSupported), false},
+		{"400", status(http.StatusBadRequest), false},
+		{"404", status(http.StatusNotFound), false},
+		{"cancelled", context.Canceled, false},
+		{"deadline", &url.Error{Op: "Get", URL: "http://localhost/", Err: context.DeadlineExceeded}, false},
+		{"circuit open", ErrCircuitOpen, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsTemporary(tt.err); got != tt.expected {
+			t.Errorf("IsTemporary(%v) for %v = %v, expected %v", tt.err, tt.name, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package directory
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup deduplicates concurrent Users.Get calls for the same key so they share a single request.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is an in-flight or completed request shared by one or more callers.
+type flightCall struct {
+	done    chan struct{}
+	waiters int
+	cancel  context.CancelFunc
+
+	user *User
+	resp *Response
+	err  error
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[string]*flightCall)}
+}
+
+// do runs fn once for all concurrent callers with the same key and hands each of them its own copy of the user.
+// A caller whose ctx is done stops waiting and gets ctx.Err(); the shared request is only cancelled once every
+// caller waiting for it has gone.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) (*User, *Response, error)) (*User, *Response, error) {
+	g.mu.Lock()
+	call, ok := g.calls[key]
+	if ok {
+		call.waiters++
+	} else {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &flightCall{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		g.calls[key] = call
+
+		go func() {
+			call.user, call.resp, call.err = fn(callCtx)
+			cancel()
+
+			g.mu.Lock()
+			delete(g.calls, key)
+			g.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.user == nil {
+			return nil, call.resp, call.err
+		}
+		user := *call.user
+		if user.Manager != nil {
+			manager := *user.Manager
+			user.Manager = &manager
+		}
+		return &user, call.resp, call.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+		}
+		g.mu.Unlock()
+		return nil, nil, ctx.Err()
+	}
+}
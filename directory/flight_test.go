@@ -0,0 +1,109 @@
+package directory
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForWaiters reports whether n callers came to share the in-flight call for key within a second.
+func waitForWaiters(g *flightGroup, key string, n int) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		g.mu.Lock()
+		call := g.calls[key]
+		ok := call != nil && call.waiters == n
+		g.mu.Unlock()
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestUsers_Get_coalesced(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var hits int32
+	release := make(chan struct{})
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		fmt.Fprint(w, userJSON)
+	})
+
+	SetCoalesceGets(true)(client)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	users := make([]*User, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			users[i], _, errs[i] = client.Users.Get(ctx, user, nil)
+		}(i)
+	}
+
+	if !waitForWaiters(client.getFlight, "employee/erick", callers) {
+		t.Errorf("Get() callers did not share the in-flight request")
+	}
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("Get() coalesced calls hit the server %d times, expected 1", got)
+	}
+	for i := range users {
+		if errs[i] != nil {
+			t.Errorf("Get() caller %d returned error: %v", i, errs[i])
+		} else if users[i].ID != "erick" {
+			t.Errorf("Get() caller %d returned %+v", i, users[i])
+		}
+	}
+	if users[0] == users[1] {
+		t.Errorf("Get() coalesced callers share the same *User")
+	}
+}
+
+func TestUsers_Get_coalescedCancel(t *testing.T) {
+	setup()
+	defer teardown()
+
+	release := make(chan struct{})
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(w, userJSON)
+	})
+
+	SetCoalesceGets(true)(client)
+
+	done := make(chan error)
+	go func() {
+		_, _, err := client.Users.Get(ctx, user, nil)
+		done <- err
+	}()
+	if !waitForWaiters(client.getFlight, "employee/erick", 1) {
+		t.Fatalf("Get() request never started")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	go func() {
+		waitForWaiters(client.getFlight, "employee/erick", 2)
+		cancel()
+	}()
+	if _, _, err := client.Users.Get(cancelled, user, nil); err != context.Canceled {
+		t.Errorf("Get() with cancelled context returned %v, expected %v", err, context.Canceled)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Get() returned error after another caller cancelled: %v", err)
+	}
+}
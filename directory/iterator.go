@@ -0,0 +1,67 @@
+package directory
+
+import (
+	"context"
+	"io"
+)
+
+// UserIterator lazily pages through the users returned by UsersService.List. Pages are fetched as Next runs out of
+// users, following the next cursor or, without one, the rel="next" Link of the previous page.
+type UserIterator struct {
+	users UsersService
+	opt   UsersListOptions
+	opts  []RequestOpt
+
+	page []*User
+	done bool
+}
+
+// NewUserIterator returns an iterator over the users listed by users with the given options. The options are
+// copied, so opt can be reused by the caller.
+func NewUserIterator(users UsersService, opt *UsersListOptions) *UserIterator {
+	it := &UserIterator{users: users}
+	if opt != nil {
+		it.opt = *opt
+	}
+
+	return it
+}
+
+// Next returns the next user, fetching the following page when needed. It returns io.EOF once every user has been
+// returned. After any other error Next can be called again to retry the failed page.
+func (it *UserIterator) Next(ctx context.Context) (*User, error) {
+	for len(it.page) == 0 {
+		if it.done {
+			return nil, io.EOF
+		}
+
+		if _, err := it.fetch(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	user := it.page[0]
+	it.page = it.page[1:]
+
+	return user, nil
+}
+
+// fetch replaces the current page with the next one and moves the iterator past it.
+func (it *UserIterator) fetch(ctx context.Context) (*Response, error) {
+	users, resp, err := it.users.List(ctx, &it.opt, it.opts...)
+	if err != nil {
+		return resp, err
+	}
+	it.page = users
+
+	if cursor := resp.NextCursor; cursor != "" {
+		it.opt.Cursor = &cursor
+		it.opts = nil
+	} else if next := resp.NextPageURL(); next != "" {
+		it.opts = []RequestOpt{WithAbsoluteURL(next)}
+	} else {
+		it.done = true
+	}
+
+	return resp, nil
+}
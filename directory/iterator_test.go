@@ -0,0 +1,102 @@
+package directory
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func testIterate(t *testing.T, it *UserIterator, expected []string) {
+	var got []string
+	for {
+		u, err := it.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() returned error: %v", err)
+		}
+		got = append(got, u.ID)
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Next() returned %v, expected %v", got, expected)
+	}
+
+	if _, err := it.Next(ctx); err != io.EOF {
+		t.Errorf("Next() after the end = %v, expected io.EOF", err)
+	}
+}
+
+func TestUserIterator_cursor(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Query().Get("fields"), "id"; got != want {
+			t.Errorf("List() fields = %q, expected the iterator to keep %q", got, want)
+		}
+
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			fmt.Fprint(w, `{"employees":[{"id":"a"},{"id":"b"}],"nextCursor":"c2"}`)
+		case "c2":
+			fmt.Fprint(w, `{"employees":[{"id":"c"}]}`)
+		}
+	})
+
+	fields := "id"
+	opt := &UsersListOptions{Fields: &fields}
+	testIterate(t, NewUserIterator(client.Users, opt), []string{"a", "b", "c"})
+
+	if opt.Cursor != nil {
+		t.Errorf("NewUserIterator() modified the caller's options")
+	}
+}
+
+func TestUserIterator_link(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%v/employee?page=2>; rel="next"`, server.URL))
+			fmt.Fprint(w, `{"employees":[{"id":"a"}]}`)
+		case "2":
+			// An empty page that still links to the next one.
+			w.Header().Set("Link", fmt.Sprintf(`<%v/employee?page=3>; rel="next"`, server.URL))
+			fmt.Fprint(w, `{"employees":[]}`)
+		case "3":
+			fmt.Fprint(w, `{"employees":[{"id":"b"}]}`)
+		}
+	})
+
+	testIterate(t, NewUserIterator(client.Users, nil), []string{"a", "b"})
+}
+
+func TestUserIterator_relativeLink(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", `</employee?page=2>; rel="next"`)
+			fmt.Fprint(w, `{"employees":[{"id":"a"}]}`)
+		case "2":
+			w.Header().Set("Link", `<employee?page=3>; rel="next"`)
+			fmt.Fprint(w, `{"employees":[{"id":"b"}]}`)
+		case "3":
+			fmt.Fprint(w, `{"employees":[{"id":"c"}]}`)
+		}
+	})
+
+	testIterate(t, NewUserIterator(client.Users, nil), []string{"a", "b", "c"})
+
+	users, _, err := client.Users.ListAllProgress(ctx, nil, nil)
+	if err != nil || len(users) != 3 {
+		t.Errorf("ListAllProgress() = %d users, %v, expected 3", len(users), err)
+	}
+}
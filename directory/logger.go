@@ -0,0 +1,81 @@
+package directory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Logger is the interface the client writes warnings to. A *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// SetLogger is a client option for receiving the warnings of the client, such as those of SetVerifyProjection.
+// Warnings are discarded when no logger is set.
+func SetLogger(l Logger) ClientOpt {
+	return func(c *Client) error {
+		c.logger = l
+		return nil
+	}
+}
+
+type loggerKey struct{}
+
+type logFieldsKey struct{}
+
+// ContextWithLogger returns a copy of ctx carrying l, which receives the warnings of the requests made with the
+// returned context instead of the logger set with SetLogger.
+func ContextWithLogger(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// ContextWithLogFields returns a copy of ctx carrying fields, such as a correlation ID, appended as key=value pairs
+// to every warning logged for the requests made with the returned context. The fields are added to those already
+// carried by ctx, replacing the values of the same keys. Users.Get adds the mmID and operation fields on its own.
+func ContextWithLogFields(ctx context.Context, fields map[string]string) context.Context {
+	merged := make(map[string]string)
+	for k, v := range logFields(ctx) {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+
+	return context.WithValue(ctx, logFieldsKey{}, merged)
+}
+
+func logFields(ctx context.Context) map[string]string {
+	fields, _ := ctx.Value(logFieldsKey{}).(map[string]string)
+	return fields
+}
+
+// logf writes a warning, followed by the log fields of ctx, to the logger of ctx or else the client logger, if any.
+func (c *Client) logf(ctx context.Context, format string, v ...interface{}) {
+	l, ok := ctx.Value(loggerKey{}).(Logger)
+	if !ok {
+		l = c.logger
+	}
+	if l == nil {
+		return
+	}
+
+	fields := logFields(ctx)
+	if len(fields) == 0 {
+		l.Printf(format, v...)
+		return
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%q", k, fields[k])
+	}
+	l.Printf("%s %s", fmt.Sprintf(format, v...), strings.Join(pairs, " "))
+}
@@ -0,0 +1,98 @@
+package directory
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// testLogger collects the lines written to it.
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestUsers_Get_verifyProjection(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"core_id":"aeg095","status":"A"}`)
+	})
+
+	logger := new(testLogger)
+	SetLogger(logger)(client)
+	SetVerifyProjection(true)(client)
+
+	fields := "coreId,fullName"
+	if _, _, err := client.Users.Get(ctx, "erick", &UsersOptions{Fields: &fields}); err != nil {
+		t.Fatalf("Users.Get() returned error: %v", err)
+	}
+
+	if len(logger.lines) != 2 {
+		t.Fatalf("Users.Get() logged %q, expected 2 warnings", logger.lines)
+	}
+	if !strings.Contains(logger.lines[0], "missing requested fields [fullName]") {
+		t.Errorf("Users.Get() logged %q, expected a warning about fullName", logger.lines[0])
+	}
+	if !strings.Contains(logger.lines[1], "not requested [status]") {
+		t.Errorf("Users.Get() logged %q, expected a warning about status", logger.lines[1])
+	}
+
+	// Without a selection every field is expected.
+	logger.lines = nil
+	client.Users.Get(ctx, "erick", nil)
+	if len(logger.lines) != 0 {
+		t.Errorf("Users.Get() without fields logged %q", logger.lines)
+	}
+}
+
+func TestContextWithLogFields(t *testing.T) {
+	setup()
+	defer teardown()
+
+	attempts := 0
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		if attempts++; attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"id":"erick"}`)
+	})
+
+	clientLogger := new(testLogger)
+	SetLogger(clientLogger)(client)
+	SetClock(newFakeClock())(client)
+	SetRetry(1)(client)
+
+	logger := new(testLogger)
+	reqCtx := ContextWithLogger(ctx, logger)
+	reqCtx = ContextWithLogFields(reqCtx, map[string]string{"correlationID": "abc-123"})
+
+	if _, _, err := client.Users.Get(reqCtx, "erick", nil); err != nil {
+		t.Fatalf("Users.Get() returned error: %v", err)
+	}
+
+	if len(logger.lines) != 1 {
+		t.Fatalf("Users.Get() logged %q, expected the retry", logger.lines)
+	}
+	for _, want := range []string{"retry 1 of 1", `correlationID="abc-123"`, `mmID="erick"`, `operation="Users.Get"`} {
+		if !strings.Contains(logger.lines[0], want) {
+			t.Errorf("Users.Get() logged %q, expected it to contain %s", logger.lines[0], want)
+		}
+	}
+	if len(clientLogger.lines) != 0 {
+		t.Errorf("client logger received %q, expected the context logger to take its place", clientLogger.lines)
+	}
+
+	// Without a context logger the client logger still gets the fields.
+	attempts = 0
+	client.Users.Get(ContextWithLogFields(ctx, map[string]string{"correlationID": "def-456"}), "erick", nil)
+	if len(clientLogger.lines) != 1 || !strings.Contains(clientLogger.lines[0], `correlationID="def-456"`) {
+		t.Errorf("client logger received %q, expected the retry with the correlation ID", clientLogger.lines)
+	}
+}
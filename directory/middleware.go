@@ -0,0 +1,33 @@
+package directory
+
+import "net/http"
+
+// ResponseMiddleware post-processes a decoded response. v is the value the response was decoded into, such as a
+// *User for Users.Get or a []*User for a page of Users.List.
+type ResponseMiddleware func(v interface{}, resp *Response) error
+
+// SetResponseMiddleware is a client option for running fn on every successfully decoded response, for example to
+// enrich users with cached data. Do calls fn after decoding into v; the services call it with the value they
+// return, so the changes made by fn are visible to the caller. An error returned by fn fails the call. Bodies
+// copied to an io.Writer are not decoded and do not go through fn.
+func SetResponseMiddleware(fn ResponseMiddleware) ClientOpt {
+	return func(c *Client) error {
+		c.responseMiddleware = fn
+		return nil
+	}
+}
+
+// postProcess runs the response middleware of c, if any, on v.
+func (c *Client) postProcess(v interface{}, resp *Response) error {
+	if c.responseMiddleware == nil {
+		return nil
+	}
+
+	return c.responseMiddleware(v, resp)
+}
+
+// decodesIntermediate marks req as decoded by Do into an internal value the caller converts before returning, so
+// Do leaves the response middleware to the caller.
+func decodesIntermediate(req *http.Request) {
+	requestOptionsFrom(req).intermediate = true
+}
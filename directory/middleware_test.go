@@ -0,0 +1,109 @@
+package directory
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestSetResponseMiddleware(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"erick"}`)
+	})
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"employees":[{"id":"erick"},{"id":"andrew"}]}`)
+	})
+
+	var seen []string
+	SetResponseMiddleware(func(v interface{}, resp *Response) error {
+		seen = append(seen, fmt.Sprintf("%T", v))
+		switch v := v.(type) {
+		case *User:
+			v.Email = v.ID + "@example.com"
+		case []*User:
+			for _, u := range v {
+				u.Email = u.ID + "@example.com"
+			}
+		}
+		return nil
+	})(client)
+
+	user, _, err := client.Users.Get(ctx, "erick", nil)
+	if err != nil {
+		t.Fatalf("Users.Get() returned error: %v", err)
+	}
+	if user.Email != "erick@example.com" {
+		t.Errorf("Users.Get() returned Email %q, expected the middleware change", user.Email)
+	}
+
+	users, _, err := client.Users.List(ctx, nil)
+	if err != nil {
+		t.Fatalf("Users.List() returned error: %v", err)
+	}
+	if users[1].Email != "andrew@example.com" {
+		t.Errorf("Users.List() returned Email %q, expected the middleware change", users[1].Email)
+	}
+
+	req, _ := client.NewRequest("GET", "employee/erick", nil)
+	user = new(User)
+	if _, err := client.Do(ctx, req, user); err != nil || user.Email != "erick@example.com" {
+		t.Errorf("Do() = %+v, %v, expected the middleware change", user, err)
+	}
+
+	// The middleware sees the value returned to the caller once, never the internal decoding.
+	if expected := "[*directory.User []*directory.User *directory.User]"; fmt.Sprint(seen) != expected {
+		t.Errorf("middleware saw %v, expected %v", seen, expected)
+	}
+}
+
+func TestSetResponseMiddleware_error(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"erick"}`)
+	})
+
+	errEnrich := errors.New("cache unavailable")
+	SetResponseMiddleware(func(v interface{}, resp *Response) error {
+		return errEnrich
+	})(client)
+
+	if user, _, err := client.Users.Get(ctx, "erick", nil); err != errEnrich || user != nil {
+		t.Errorf("Users.Get() = %+v, %v, expected %v", user, err, errEnrich)
+	}
+}
+
+func TestSetResponseMiddleware_createMinimal(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	var seen []*User
+	SetResponseMiddleware(func(v interface{}, resp *Response) error {
+		if u, ok := v.(*User); ok {
+			u.Email = u.ID + "@example.com"
+			seen = append(seen, u)
+		}
+		return nil
+	})(client)
+
+	input := &User{ID: "erick"}
+	user, _, err := client.Users.Create(ctx, input, WithPrefer(PreferMinimal))
+	if err != nil {
+		t.Fatalf("Users.Create() returned error: %v", err)
+	}
+	if user != input || user.Email != "erick@example.com" {
+		t.Errorf("Users.Create() returned %+v, expected the input user with the middleware change", user)
+	}
+	if len(seen) != 1 || seen[0] != user {
+		t.Errorf("middleware saw %+v, expected only the returned user", seen)
+	}
+}
@@ -0,0 +1,58 @@
+package directory
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// nameParticles are the words kept in lower case inside a name, like "de" in "Juan de la Cruz".
+var nameParticles = map[string]bool{
+	"da": true, "das": true, "de": true, "del": true, "der": true, "di": true, "do": true, "dos": true,
+	"du": true, "la": true, "le": true, "van": true, "von": true, "y": true,
+}
+
+// NormalizedName returns FullName in title case for display, whatever the casing stored in the directory:
+// "ERICK GUEVARA" and "erick guevara" both become "Erick Guevara". Every part of a hyphenated name is capitalized,
+// as is the letter after an O' or D' prefix, and particles such as "de" or "van" stay in lower case unless they
+// start the name. Surrounding and repeated spaces are dropped. The stored FullName is not modified.
+func (u *User) NormalizedName() string {
+	words := strings.Fields(u.FullName)
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if i > 0 && nameParticles[word] {
+			words[i] = word
+			continue
+		}
+
+		parts := strings.Split(word, "-")
+		for j, part := range parts {
+			parts[j] = capitalizeNamePart(part)
+		}
+		words[i] = strings.Join(parts, "-")
+	}
+
+	return strings.Join(words, " ")
+}
+
+// capitalizeNamePart upper cases the first letter of the lower case name part p, and the letter following an
+// apostrophe after a one letter prefix (O'Brien, D'Angelo).
+func capitalizeNamePart(p string) string {
+	p = upperFirst(p)
+
+	if i := strings.IndexAny(p, "'’"); i > 0 && utf8.RuneCountInString(p[:i]) == 1 {
+		_, size := utf8.DecodeRuneInString(p[i:])
+		p = p[:i+size] + upperFirst(p[i+size:])
+	}
+
+	return p
+}
+
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+
+	return string(unicode.ToUpper(r)) + s[size:]
+}
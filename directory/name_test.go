@@ -0,0 +1,31 @@
+package directory
+
+import "testing"
+
+func TestUser_NormalizedName(t *testing.T) {
+	tests := []struct {
+		fullName string
+		expected string
+	}{
+		{"ERICK GUEVARA", "Erick Guevara"},
+		{"erick guevara", "Erick Guevara"},
+		{"  eRiCk   guevara ", "Erick Guevara"},
+		{"mary-jane WATSON-PARKER", "Mary-Jane Watson-Parker"},
+		{"JUAN DE LA CRUZ", "Juan de la Cruz"},
+		{"ludwig VAN beethoven", "Ludwig van Beethoven"},
+		{"de la hoya oscar", "De la Hoya Oscar"},
+		{"SEAN O'BRIEN", "Sean O'Brien"},
+		{"ÉLODIE ÇELIK", "Élodie Çelik"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		u := &User{FullName: tt.fullName}
+		if got := u.NormalizedName(); got != tt.expected {
+			t.Errorf("NormalizedName() of %q = %q, expected %q", tt.fullName, got, tt.expected)
+		}
+		if u.FullName != tt.fullName {
+			t.Errorf("NormalizedName() modified FullName to %q", u.FullName)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package directory
+
+// Page is one page of the results of a list call, the uniform paging result of the services.
+type Page[T any] struct {
+	// Items of the page, in the order returned by the API.
+	Items []T
+
+	// NextCursor is the opaque cursor for the next page. An empty NextCursor means there are no more results.
+	NextCursor string
+
+	// NextPageURL is the rel="next" Link of the response, for APIs that page with Link headers instead of cursors.
+	// The next page is fetched by passing it to WithAbsoluteURL.
+	NextPageURL string
+
+	// Total is the number of results across all pages, or -1 when the API does not report it.
+	Total int
+}
+
+// HasNext reports whether there is a page after p, by cursor or by Link header.
+func (p *Page[T]) HasNext() bool {
+	return p.NextCursor != "" || p.NextPageURL != ""
+}
+
+// newPage returns the page of items listed in resp.
+func newPage[T any](items []T, resp *Response) *Page[T] {
+	p := &Page[T]{Items: items, NextCursor: resp.NextCursor, NextPageURL: resp.NextPageURL(), Total: -1}
+	if resp.Total != nil {
+		p.Total = *resp.Total
+	}
+
+	return p
+}
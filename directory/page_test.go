@@ -0,0 +1,73 @@
+package directory
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestUsers_ListPage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			fmt.Fprint(w, `{"employees":[{"id":"a"},{"id":"b"}],"total":3,"nextCursor":"c2"}`)
+		case "c2":
+			fmt.Fprint(w, `{"employees":[{"id":"c"}]}`)
+		}
+	})
+
+	page, _, err := client.Users.ListPage(ctx, nil)
+	if err != nil {
+		t.Fatalf("Users.ListPage() returned error: %v", err)
+	}
+
+	expected := &Page[*User]{Items: []*User{{ID: "a"}, {ID: "b"}}, NextCursor: "c2", Total: 3}
+	if !reflect.DeepEqual(page, expected) {
+		t.Errorf("Users.ListPage() returned %+v, expected %+v", page, expected)
+	}
+	if !page.HasNext() {
+		t.Errorf("HasNext() = false, expected true")
+	}
+
+	page, _, err = client.Users.ListPage(ctx, &UsersListOptions{Cursor: &page.NextCursor})
+	if err != nil {
+		t.Fatalf("Users.ListPage() returned error: %v", err)
+	}
+	if page.HasNext() || page.Total != -1 || len(page.Items) != 1 {
+		t.Errorf("Users.ListPage() returned %+v, expected a last page of one user without a total", page)
+	}
+}
+
+func TestUsers_ListPage_link(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", `</employee?page=2>; rel="next"`)
+			fmt.Fprint(w, `{"employees":[{"id":"a"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"employees":[{"id":"b"}]}`)
+	})
+
+	page, _, err := client.Users.ListPage(ctx, nil)
+	if err != nil {
+		t.Fatalf("Users.ListPage() returned error: %v", err)
+	}
+	if !page.HasNext() || page.NextPageURL != server.URL+"/employee?page=2" {
+		t.Fatalf("Users.ListPage() returned %+v, expected a next page link", page)
+	}
+
+	page, _, err = client.Users.ListPage(ctx, nil, WithAbsoluteURL(page.NextPageURL))
+	if err != nil {
+		t.Fatalf("Users.ListPage() returned error: %v", err)
+	}
+	if page.HasNext() || len(page.Items) != 1 || page.Items[0].ID != "b" {
+		t.Errorf("Users.ListPage() returned %+v, expected a last page with b", page)
+	}
+}
@@ -0,0 +1,52 @@
+package directory
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// Parallel runs ops concurrently under a context derived from ctx and waits for all of them. Each op stores its own
+// results, typically in variables captured by the closure.
+//
+// An op failing with a not-found error, ErrNotFound or an *ErrorResponse for a 404, is a soft failure: the other ops
+// keep running and the error is not returned. Any other error is hard: the shared context is cancelled so the ops
+// still in flight stop early, and the first hard error is returned once every op has returned.
+func (c *Client) Parallel(ctx context.Context, ops ...func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		once sync.Once
+		hard error
+		wg   sync.WaitGroup
+	)
+
+	for _, op := range ops {
+		wg.Add(1)
+		go func(op func(ctx context.Context) error) {
+			defer wg.Done()
+
+			if err := op(ctx); err != nil && !isNotFound(err) {
+				once.Do(func() {
+					hard = err
+					cancel()
+				})
+			}
+		}(op)
+	}
+	wg.Wait()
+
+	return hard
+}
+
+// isNotFound reports whether err means the requested resource does not exist.
+func isNotFound(err error) bool {
+	if errors.Is(err, ErrNotFound) {
+		return true
+	}
+
+	var errResp *ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,74 @@
+package directory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestClient_Parallel(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"erick","manager":{"id":"andrew"}}`)
+	})
+	mux.HandleFunc("/employee/andrew", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"andrew"}`)
+	})
+	mux.HandleFunc("/employee/ghost", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"code":404,"message":"not found"}}`, http.StatusNotFound)
+	})
+
+	var user, manager, missing *User
+	err := client.Parallel(ctx,
+		func(ctx context.Context) (err error) {
+			user, _, err = client.Users.Get(ctx, "erick", nil)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			manager, _, err = client.Users.Get(ctx, "andrew", nil)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			missing, _, err = client.Users.Get(ctx, "ghost", nil)
+			return err
+		},
+	)
+	if err != nil {
+		t.Fatalf("Parallel() returned error: %v", err)
+	}
+	if user == nil || user.ID != "erick" || manager == nil || manager.ID != "andrew" || missing != nil {
+		t.Errorf("Parallel() results = %+v, %+v, %+v, expected erick, andrew and nil", user, manager, missing)
+	}
+}
+
+func TestClient_Parallel_hardError(t *testing.T) {
+	setup()
+	defer teardown()
+
+	errHard := errors.New("boom")
+
+	cancelled := make(chan error, 1)
+	err := client.Parallel(ctx,
+		func(ctx context.Context) error {
+			<-ctx.Done()
+			cancelled <- ctx.Err()
+			return ctx.Err()
+		},
+		func(ctx context.Context) error {
+			return fmt.Errorf("lookup: %w", ErrNotFound)
+		},
+		func(ctx context.Context) error {
+			return errHard
+		},
+	)
+	if err != errHard {
+		t.Errorf("Parallel() error = %v, expected %v", err, errHard)
+	}
+	if err := <-cancelled; err != context.Canceled {
+		t.Errorf("pending op context error = %v, expected %v", err, context.Canceled)
+	}
+}
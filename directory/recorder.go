@@ -0,0 +1,158 @@
+package directory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// Interaction is a recorded HTTP request and response pair.
+type Interaction struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	RequestBody string      `json:"requestBody,omitempty"`
+	StatusCode  int         `json:"statusCode"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body,omitempty"`
+}
+
+// key identifies the request of an interaction by method, path and query.
+func (i *Interaction) key() string {
+	return i.Method + " " + i.URL
+}
+
+func requestKey(req *http.Request) string {
+	return req.Method + " " + req.URL.RequestURI()
+}
+
+// recorder is an http.RoundTripper that writes every interaction to its recording.
+type recorder struct {
+	transport http.RoundTripper
+	rec       *recording
+}
+
+// recording is where the recorders of a client write, every interaction as a line of JSON.
+type recording struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	i := Interaction{Method: req.Method, URL: req.URL.RequestURI()}
+
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		i.RequestBody = string(body)
+
+		// A RoundTripper must not modify the request it was given, so the buffered body goes on a copy.
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	i.StatusCode = resp.StatusCode
+	i.Header = resp.Header
+	i.Body = string(body)
+
+	r.rec.mu.Lock()
+	defer r.rec.mu.Unlock()
+	if err := r.rec.enc.Encode(&i); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// replayer is an http.RoundTripper that answers requests from recorded interactions. Interactions matching the
+// same request are served in the order they were recorded.
+type replayer struct {
+	mu           sync.Mutex
+	interactions map[string][]Interaction
+}
+
+func (r *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := requestKey(req)
+	queue := r.interactions[key]
+	if len(queue) == 0 {
+		return nil, fmt.Errorf("no recorded interaction for %v", key)
+	}
+	i := queue[0]
+	r.interactions[key] = queue[1:]
+
+	header := i.Header
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.StatusCode, http.StatusText(i.StatusCode)),
+		StatusCode:    i.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(i.Body))),
+		ContentLength: int64(len(i.Body)),
+		Request:       req,
+	}, nil
+}
+
+// SetRecording is a client option that records every request and response to w, one JSON encoded Interaction
+// per line. The recording can be served back with SetReplay.
+func SetRecording(w io.Writer) ClientOpt {
+	return func(c *Client) error {
+		rec := &recording{enc: json.NewEncoder(w)}
+		c.wrapTransport(func(rt http.RoundTripper) http.RoundTripper {
+			return &recorder{transport: rt, rec: rec}
+		})
+		return nil
+	}
+}
+
+// SetReplay is a client option that serves responses from a recording made with SetRecording instead of
+// contacting the directory API. Requests are matched by method, path and query; a request with no recorded
+// interaction left fails.
+func SetReplay(r io.Reader) ClientOpt {
+	return func(c *Client) error {
+		rp := &replayer{interactions: map[string][]Interaction{}}
+
+		dec := json.NewDecoder(r)
+		for {
+			var i Interaction
+			if err := dec.Decode(&i); err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+
+			rp.interactions[i.key()] = append(rp.interactions[i.key()], i)
+		}
+
+		c.wrapTransport(func(http.RoundTripper) http.RoundTripper {
+			return rp
+		})
+		return nil
+	}
+}
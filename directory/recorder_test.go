@@ -0,0 +1,145 @@
+package directory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordReplay(t *testing.T) {
+	setup()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Query().Get("fields"), "id"; got != want {
+			t.Errorf("Request fields = %q, expected %q", got, want)
+		}
+		fmt.Fprint(w, userJSON)
+	})
+
+	var buf bytes.Buffer
+	rec, err := New(SetBaseURL(server.URL), SetRecording(&buf))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	fields := "id"
+	opt := &UsersOptions{Fields: &fields}
+	recorded, _, err := rec.Users.Get(ctx, "erick", opt)
+	if err != nil {
+		t.Fatalf("Get() while recording returned error: %v", err)
+	}
+
+	// The backend is gone; the replay must be served from the recording.
+	teardown()
+
+	rep, err := New(SetBaseURL(server.URL), SetReplay(&buf))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	replayed, resp, err := rep.Users.Get(ctx, "erick", opt)
+	if err != nil {
+		t.Fatalf("Get() while replaying returned error: %v", err)
+	}
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("Get() replayed %+v, expected %+v", replayed, recorded)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Get() replayed status %v, expected %v", resp.StatusCode, http.StatusOK)
+	}
+
+	// The only matching interaction has been used up.
+	if _, _, err := rep.Users.Get(ctx, "erick", opt); err == nil {
+		t.Errorf("Get() expected error for an interaction that was not recorded")
+	}
+}
+
+func TestSetReplay_badRecording(t *testing.T) {
+	_, err := New(SetBaseURL("http://localhost/"), SetReplay(strings.NewReader("{")))
+	if err == nil {
+		t.Errorf("SetReplay() expected error for a malformed recording")
+	}
+}
+
+func TestRecorder_keepsRequest(t *testing.T) {
+	var sent io.Reader
+	r := &recorder{
+		transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			sent = req.Body
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}),
+		rec: &recording{enc: json.NewEncoder(ioutil.Discard)},
+	}
+
+	body := ioutil.NopCloser(strings.NewReader(`{"id":"erick"}`))
+	req, _ := http.NewRequest("POST", "http://localhost/employee", body)
+	if _, err := r.RoundTrip(req); err != nil {
+		t.Fatalf("RoundTrip() returned error: %v", err)
+	}
+	if req.Body != body {
+		t.Errorf("RoundTrip() replaced the body of the caller's request")
+	}
+	if got, _ := ioutil.ReadAll(sent); string(got) != `{"id":"erick"}` {
+		t.Errorf("RoundTrip() sent body %q, expected the recorded one", got)
+	}
+}
+
+func TestSetReplay_optionOrder(t *testing.T) {
+	const recording = `{"method":"GET","url":"/employee/erick","statusCode":200,"body":"{\"id\":\"erick\"}"}` + "\n"
+	hc := &http.Client{Transport: http.DefaultTransport}
+
+	orders := map[string][]ClientOpt{
+		"replay first":      {SetReplay(strings.NewReader(recording)), SetHTTPClient(hc), SetHTTP2(true)},
+		"http client first": {SetHTTPClient(hc), SetHTTP2(true), SetReplay(strings.NewReader(recording))},
+	}
+	for name, opts := range orders {
+		// Nothing listens on the base URL, so only a replayed response can succeed.
+		c, err := New(append([]ClientOpt{SetBaseURL("http://127.0.0.1:1/")}, opts...)...)
+		if err != nil {
+			t.Errorf("New() %v returned error: %v", name, err)
+			continue
+		}
+
+		got, _, err := c.Users.Get(ctx, "erick", nil)
+		if err != nil || got.ID != "erick" {
+			t.Errorf("Get() %v = %+v, %v, expected the replayed erick", name, got, err)
+		}
+	}
+}
+
+func TestSetRecording_optionOrder(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"erick"}`)
+	})
+
+	for _, recordFirst := range []bool{true, false} {
+		var buf bytes.Buffer
+		opts := []ClientOpt{SetHTTPClient(&http.Client{}), SetResponseHeaderTimeout(time.Second)}
+		if recordFirst {
+			opts = append([]ClientOpt{SetRecording(&buf)}, opts...)
+		} else {
+			opts = append(opts, SetRecording(&buf))
+		}
+
+		c, err := New(append([]ClientOpt{SetBaseURL(server.URL)}, opts...)...)
+		if err != nil {
+			t.Fatalf("New() recording first %v returned error: %v", recordFirst, err)
+		}
+		if _, _, err := c.Users.Get(ctx, "erick", nil); err != nil {
+			t.Fatalf("Get() recording first %v returned error: %v", recordFirst, err)
+		}
+		if !strings.Contains(buf.String(), `"url":"/employee/erick"`) {
+			t.Errorf("recording first %v recorded %q, expected the Get", recordFirst, buf.String())
+		}
+	}
+}
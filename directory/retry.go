@@ -0,0 +1,309 @@
+package directory
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backoff used between retries by default: it starts at defaultRetryBackoff and grows by defaultRetryFactor on
+// every retry up to defaultRetryMaxBackoff, with full jitter.
+const (
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultRetryMaxBackoff = 5 * time.Second
+	defaultRetryFactor     = 2
+	defaultRetryJitter     = 1
+)
+
+// retryPolicy holds the retry settings of a Client.
+type retryPolicy struct {
+	// Maximum number of retries after the first attempt.
+	max int
+
+	backoff    time.Duration
+	maxBackoff time.Duration
+	factor     float64
+	// Fraction of the backoff randomly taken off every delay, from 0 for none to 1 for full jitter.
+	jitter float64
+}
+
+// defaultRetryPolicy returns the retry settings of a new Client: no retries, with the default backoff once retries
+// are enabled.
+func defaultRetryPolicy() retryPolicy {
+	return retryPolicy{
+		backoff:    defaultRetryBackoff,
+		maxBackoff: defaultRetryMaxBackoff,
+		factor:     defaultRetryFactor,
+		jitter:     defaultRetryJitter,
+	}
+}
+
+// SetRetry is a client option for retrying a request up to max times, with exponential backoff, after a transport
+// error or a 429 or 5xx response. A Retry-After header on the response takes the place of the backoff. Requests
+// with a body that can not be rewound are not retried. The backoff can be tuned with SetRetryBackoff.
+//
+// Only idempotent requests are retried: GET, HEAD and OPTIONS requests, and requests of any method sent with
+// an idempotency key (see WithIdempotencyKey), since resending a write the server already processed would
+// repeat it. SetRetryNonIdempotent lifts that restriction.
+func SetRetry(max int) ClientOpt {
+	return func(c *Client) error {
+		if max < 0 {
+			return fmt.Errorf("retry max can not be negative, got %d", max)
+		}
+
+		c.retry.max = max
+		return nil
+	}
+}
+
+// Backoff configures the delay between retries. The delay before retry n, starting at 0, is Base * Factor^n capped
+// at Max, less a random part of up to Jitter times that delay. Every zero field keeps its default: 100ms for Base,
+// 2 for Factor, 5s for Max and full jitter, where the delay is anywhere between 0 and the computed value. Jitter
+// ranges from 0 to 1; set NoJitter for a fixed delay.
+type Backoff struct {
+	Base   time.Duration
+	Factor float64
+	Max    time.Duration
+	Jitter float64
+
+	// NoJitter disables jitter, so every delay is exactly the computed value.
+	NoJitter bool
+}
+
+// SetRetryBackoff is a client option for tuning the backoff between the retries enabled with SetRetry. By default
+// full jitter is applied, so many clients retrying after the same outage spread their retries instead of hitting
+// the backend at once.
+func SetRetryBackoff(b Backoff) ClientOpt {
+	return func(c *Client) error {
+		switch {
+		case b.Base < 0 || b.Max < 0:
+			return fmt.Errorf("retry backoff can not be negative, got %v up to %v", b.Base, b.Max)
+		case b.Factor != 0 && b.Factor < 1:
+			return fmt.Errorf("retry backoff factor must be at least 1, got %v", b.Factor)
+		case b.Jitter < 0 || b.Jitter > 1:
+			return fmt.Errorf("retry backoff jitter must be between 0 and 1, got %v", b.Jitter)
+		case b.NoJitter && b.Jitter != 0:
+			return fmt.Errorf("retry backoff jitter %v can not be combined with NoJitter", b.Jitter)
+		}
+
+		p := defaultRetryPolicy()
+		if b.Base > 0 {
+			p.backoff = b.Base
+		}
+		if b.Factor > 0 {
+			p.factor = b.Factor
+		}
+		if b.Max > 0 {
+			p.maxBackoff = b.Max
+		}
+		switch {
+		case b.NoJitter:
+			p.jitter = 0
+		case b.Jitter > 0:
+			p.jitter = b.Jitter
+		}
+		p.max = c.retry.max
+
+		c.retry = p
+		return nil
+	}
+}
+
+// SetRetryNonIdempotent is a client option for retrying requests of every method, such as a POST without an
+// idempotency key, instead of only the idempotent ones. It should only be enabled when the API deduplicates writes
+// on its own.
+func SetRetryNonIdempotent(enabled bool) ClientOpt {
+	return func(c *Client) error {
+		c.retryNonIdempotent = enabled
+		return nil
+	}
+}
+
+// idempotencyKeyHeader carries the key the API uses to apply a write only once.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// WithIdempotencyKey is a request option for sending key in the Idempotency-Key header, so the API applies the
+// request only once however many times it is received, which makes it safe to retry whatever its method.
+func WithIdempotencyKey(key string) RequestOpt {
+	return func(o *requestOptions) error {
+		o.header.Set(idempotencyKeyHeader, key)
+		return nil
+	}
+}
+
+// idempotent reports whether sending req more than once has the same effect as sending it once.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	return req.Header.Get(idempotencyKeyHeader) != ""
+}
+
+// WithRetry is a request option for retrying a single request up to max times instead of the number set with
+// SetRetry; WithRetry(0) disables retries for the request. The retry budget of the client still applies.
+func WithRetry(max int) RequestOpt {
+	return func(o *requestOptions) error {
+		if max < 0 {
+			return fmt.Errorf("retry max can not be negative, got %d", max)
+		}
+
+		o.retryMax = &max
+		return nil
+	}
+}
+
+// delay returns the backoff before the given retry, starting at 0, with its jitter applied.
+func (p retryPolicy) delay(retry int) time.Duration {
+	d := float64(p.backoff)
+	for i := 0; i < retry && d < float64(p.maxBackoff); i++ {
+		d *= p.factor
+	}
+	if d > float64(p.maxBackoff) {
+		d = float64(p.maxBackoff)
+	}
+	if p.jitter > 0 {
+		d -= d * p.jitter * rand.Float64()
+	}
+
+	return time.Duration(d)
+}
+
+// retryBudget is a token bucket of retries shared by all the requests of a Client.
+type retryBudget struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	// Tokens added per second.
+	rate float64
+	last time.Time
+}
+
+// SetRetryBudget is a client option for allowing at most n retries per window across all the requests of the
+// client, so a widespread outage does not multiply the load on the backend. Once the budget is spent failures are
+// returned right away, without retrying, until it refills.
+func SetRetryBudget(n int, window time.Duration) ClientOpt {
+	return func(c *Client) error {
+		if n < 0 || window <= 0 {
+			return fmt.Errorf("invalid retry budget of %d per %v", n, window)
+		}
+
+		c.retryBudget = &retryBudget{capacity: float64(n), tokens: float64(n), rate: float64(n) / window.Seconds()}
+		return nil
+	}
+}
+
+// take spends one retry from the budget at now, reporting false when none is left.
+func (b *retryBudget) take(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+
+	return true
+}
+
+// roundTrip sends req, retrying it according to the retry policy of the request or the client and the client budget.
+// Every attempt is counted in attempts.
+func (c *Client) roundTrip(ctx context.Context, req *http.Request, ro *requestOptions, attempts *int) (*http.Response, error) {
+	policy := c.retry
+	if ro.retryMax != nil {
+		policy.max = *ro.retryMax
+	}
+
+	if !c.retryNonIdempotent && !idempotent(req) {
+		policy.max = 0
+	}
+
+	for retry := 0; ; retry++ {
+		*attempts++
+		resp, err := c.send(ctx, req)
+		if retry >= policy.max || !retryable(ctx, req, resp, err) {
+			return resp, err
+		}
+		if c.retryBudget != nil && !c.retryBudget.take(c.clock.Now()) {
+			return resp, err
+		}
+
+		delay := policy.delay(retry)
+		if resp != nil {
+			if d := parseRetryAfter(resp.Header.Get("Retry-After"), c.clock.Now()); d > 0 {
+				delay = d
+			}
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		c.logf(ctx, "directory: %v %v: retry %d of %d in %v", req.Method, req.URL, retry+1, policy.max, delay)
+
+		select {
+		case <-c.clock.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// parseRetryAfter returns the delay requested by a Retry-After header value h, either a number of seconds or an
+// HTTP-date relative to now. It returns 0, leaving the backoff to the caller, for an empty or malformed value or a
+// date in the past.
+func parseRetryAfter(h string, now time.Time) time.Duration {
+	h = strings.TrimSpace(h)
+	if h == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(h); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(h); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// retryable reports whether the outcome of sending req is worth another attempt.
+func retryable(ctx context.Context, req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if err != nil {
+		return ctx.Err() == nil && IsTemporary(err)
+	}
+
+	return temporaryStatus(resp.StatusCode)
+}
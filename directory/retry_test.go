@@ -0,0 +1,360 @@
+package directory
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDo_retry(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var bodies []string
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	clock := newFakeClock()
+	SetClock(clock)(client)
+	SetRetry(3)(client)
+	// Without jitter the delays are exact.
+	SetRetryBackoff(Backoff{NoJitter: true})(client)
+
+	req, _ := client.NewRequest("POST", "/", &User{ID: "erick"}, WithIdempotencyKey("create-erick"))
+	resp, err := client.Do(ctx, req, nil)
+	if err != nil {
+		t.Fatalf("Do() returned error after retries: %v", err)
+	}
+	if resp.Attempts != 3 {
+		t.Errorf("Do() Attempts = %d, expected 3", resp.Attempts)
+	}
+
+	expected := `{"coreId":"","fullName":"","status":"","id":"erick"}` + "\n"
+	if !reflect.DeepEqual(bodies, []string{expected, expected, expected}) {
+		t.Errorf("Do() sent bodies %q, expected the body rewound on every attempt", bodies)
+	}
+	if got, want := clock.Waits(), []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Do() waited %v between attempts, expected %v", got, want)
+	}
+}
+
+func TestDo_retryClientError(t *testing.T) {
+	setup()
+	defer teardown()
+
+	hits := 0
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	SetClock(newFakeClock())(client)
+	SetRetry(3)(client)
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	resp, err := client.Do(ctx, req, nil)
+	if err == nil {
+		t.Errorf("Do() expected error")
+	}
+	if hits != 1 || resp.Attempts != 1 {
+		t.Errorf("Do() retried a 400 response, hits = %d, Attempts = %d", hits, resp.Attempts)
+	}
+}
+
+func TestDo_retryMatchesIsTemporary(t *testing.T) {
+	setup()
+	defer teardown()
+
+	SetClock(newFakeClock())(client)
+	SetRetry(1)(client)
+
+	for _, code := range []int{http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusNotImplemented, http.StatusBadGateway, http.StatusHTTPVersionNotSupported} {
+		path := fmt.Sprintf("/status/%d", code)
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		})
+
+		req, _ := client.NewRequest("GET", path, nil)
+		resp, err := client.Do(ctx, req, nil)
+		if retried := resp.Attempts > 1; retried != IsTemporary(err) {
+			t.Errorf("Do() for %d made %d attempts, but IsTemporary() = %v", code, resp.Attempts, IsTemporary(err))
+		}
+	}
+}
+
+func TestDo_retryRedirectLoop(t *testing.T) {
+	setup()
+	defer teardown()
+
+	hits := 0
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Location", "/")
+		w.WriteHeader(http.StatusFound)
+	})
+
+	SetClock(newFakeClock())(client)
+	SetMaxRedirects(1)(client)
+	SetRetry(3)(client)
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	if _, err := client.Do(ctx, req, nil); !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("Do() error = %v, expected %v", err, ErrTooManyRedirects)
+	}
+	if hits != 2 {
+		t.Errorf("Do() retried a redirect loop, hits = %d, expected 2", hits)
+	}
+}
+
+func TestDo_retryIdempotentOnly(t *testing.T) {
+	setup()
+	defer teardown()
+
+	hits := map[string]int{}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		hits[r.Method]++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	SetClock(newFakeClock())(client)
+	SetRetry(2)(client)
+
+	for _, method := range []string{"GET", "POST"} {
+		req, _ := client.NewRequest(method, "/", nil)
+		if _, err := client.Do(ctx, req, nil); err == nil {
+			t.Errorf("Do(%v) expected error", method)
+		}
+	}
+	if expected := map[string]int{"GET": 3, "POST": 1}; !reflect.DeepEqual(hits, expected) {
+		t.Errorf("Do() sent %v, expected only the GET retried", hits)
+	}
+
+	hits = map[string]int{}
+	SetRetryNonIdempotent(true)(client)
+	req, _ := client.NewRequest("POST", "/", nil)
+	client.Do(ctx, req, nil)
+	if hits["POST"] != 3 {
+		t.Errorf("Do(POST) with SetRetryNonIdempotent sent %d requests, expected 3", hits["POST"])
+	}
+}
+
+func TestRetryPolicy_delay(t *testing.T) {
+	p := retryPolicy{backoff: time.Second, maxBackoff: 5 * time.Second, factor: 2}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for retry, want := range expected {
+		if got := p.delay(retry); got != want {
+			t.Errorf("delay(%d) = %v, expected %v", retry, got, want)
+		}
+	}
+}
+
+func TestDo_retryJitter(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	clock := newFakeClock()
+	SetClock(clock)(client)
+	SetRetry(4)(client)
+	SetRetryBackoff(Backoff{Base: time.Second, Factor: 3, Max: 5 * time.Second, Jitter: 0.5})(client)
+
+	var waits []time.Duration
+	for i := 0; i < 5; i++ {
+		req, _ := client.NewRequest("GET", "/", nil)
+		client.Do(ctx, req, nil)
+		waits = clock.Waits()
+	}
+
+	// Each request waits before its 4 retries: 1s, 3s, 5s and 5s, less up to half of it.
+	caps := []time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 5 * time.Second}
+	if len(waits) != 5*len(caps) {
+		t.Fatalf("Do() waited %d times, expected %d", len(waits), 5*len(caps))
+	}
+	distinct := map[time.Duration]bool{}
+	for i, d := range waits {
+		max := caps[i%len(caps)]
+		if d < max/2 || d > max {
+			t.Errorf("Do() wait %d = %v, expected between %v and %v", i, d, max/2, max)
+		}
+		distinct[d] = true
+	}
+	if len(distinct) < 2 {
+		t.Errorf("Do() waited %v, expected the jitter to vary the delays", waits)
+	}
+}
+
+func TestSetRetryBackoff_defaultJitter(t *testing.T) {
+	c := NewClient()
+	SetRetryBackoff(Backoff{Base: 200 * time.Millisecond})(c)
+	if c.retry.backoff != 200*time.Millisecond || c.retry.jitter != defaultRetryJitter {
+		t.Errorf("SetRetryBackoff() policy = %+v, expected the base changed and full jitter kept", c.retry)
+	}
+
+	SetRetryBackoff(Backoff{NoJitter: true})(c)
+	if c.retry.jitter != 0 {
+		t.Errorf("SetRetryBackoff(NoJitter) jitter = %v, expected 0", c.retry.jitter)
+	}
+}
+
+func TestSetRetryBackoff_invalid(t *testing.T) {
+	for _, b := range []Backoff{{Base: -time.Second}, {Factor: 0.5}, {Jitter: 1.5}, {Jitter: 0.5, NoJitter: true}} {
+		if _, err := New(SetBaseURL("http://localhost/"), SetRetryBackoff(b)); err == nil {
+			t.Errorf("SetRetryBackoff(%+v) expected error", b)
+		}
+	}
+}
+
+func TestDo_retryBudget(t *testing.T) {
+	setup()
+	defer teardown()
+
+	hits := 0
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	clock := newFakeClock()
+	SetClock(clock)(client)
+	SetRetry(3)(client)
+	// Without jitter the delays are exact.
+	SetRetryBackoff(Backoff{NoJitter: true})(client)
+	SetRetryBudget(2, time.Minute)(client)
+
+	do := func() int {
+		hits = 0
+		req, _ := client.NewRequest("GET", "/", nil)
+		if _, err := client.Do(ctx, req, nil); err == nil {
+			t.Errorf("Do() expected error")
+		}
+		return hits
+	}
+
+	// The budget allows two of the three retries.
+	if got := do(); got != 3 {
+		t.Errorf("Do() with budget of 2 made %d attempts, expected 3", got)
+	}
+
+	// The budget is spent: fail fast.
+	if got := do(); got != 1 {
+		t.Errorf("Do() with spent budget made %d attempts, expected 1", got)
+	}
+
+	clock.Advance(time.Minute)
+	if got := do(); got != 3 {
+		t.Errorf("Do() with refilled budget made %d attempts, expected 3", got)
+	}
+}
+
+func TestSetRetry_invalid(t *testing.T) {
+	if _, err := New(SetBaseURL("http://localhost/"), SetRetry(-1)); err == nil {
+		t.Errorf("SetRetry(-1) expected error")
+	}
+	if _, err := New(SetBaseURL("http://localhost/"), SetRetryBudget(1, 0)); err == nil {
+		t.Errorf("SetRetryBudget() expected error for an empty window")
+	}
+}
+
+func TestDo_withRetry(t *testing.T) {
+	setup()
+	defer teardown()
+
+	hits := 0
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	SetClock(newFakeClock())(client)
+	SetRetry(3)(client)
+
+	req, _ := client.NewRequest("GET", "/", nil, WithRetry(0))
+	if _, err := client.Do(ctx, req, nil); err == nil {
+		t.Errorf("Do() expected error")
+	}
+	if hits != 1 {
+		t.Errorf("Do() with WithRetry(0) made %d attempts, expected 1", hits)
+	}
+
+	// A request can also retry more than the client default.
+	hits = 0
+	SetRetry(0)(client)
+	req, _ = client.NewRequest("GET", "/", nil, WithRetry(2))
+	client.Do(ctx, req, nil)
+	if hits != 3 {
+		t.Errorf("Do() with WithRetry(2) made %d attempts, expected 3", hits)
+	}
+
+	if _, err := client.NewRequest("GET", "/", nil, WithRetry(-1)); err == nil {
+		t.Errorf("NewRequest() expected error for a negative retry max")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2015, time.October, 21, 7, 28, 0, 0, time.UTC)
+
+	tests := []struct {
+		header   string
+		expected time.Duration
+	}{
+		{"120", 2 * time.Minute},
+		{" 3 ", 3 * time.Second},
+		{"Wed, 21 Oct 2015 07:28:30 GMT", 30 * time.Second},
+		{"Wed, 21 Oct 2015 07:27:00 GMT", 0},
+		{"", 0},
+		{"-5", 0},
+		{"soon", 0},
+		{"1.5", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.header, now); got != tt.expected {
+			t.Errorf("parseRetryAfter(%q) = %v, expected %v", tt.header, got, tt.expected)
+		}
+	}
+}
+
+func TestDo_retryAfter(t *testing.T) {
+	setup()
+	defer teardown()
+
+	headers := []string{"7", "soon"}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if len(headers) == 0 {
+			return
+		}
+		w.Header().Set("Retry-After", headers[0])
+		headers = headers[1:]
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	clock := newFakeClock()
+	SetClock(clock)(client)
+	SetRetry(3)(client)
+	// Without jitter the delays are exact.
+	SetRetryBackoff(Backoff{NoJitter: true})(client)
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	if _, err := client.Do(ctx, req, nil); err != nil {
+		t.Fatalf("Do() returned error after retries: %v", err)
+	}
+
+	// The malformed value falls back to the backoff of the second retry.
+	if got, want := clock.Waits(), []time.Duration{7 * time.Second, 200 * time.Millisecond}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Do() waited %v between attempts, expected %v", got, want)
+	}
+}
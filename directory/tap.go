@@ -0,0 +1,34 @@
+package directory
+
+import "io"
+
+// SetResponseTap is a client option for copying every response body Do reads to w, such as an audit pipeline,
+// while it is decoded as usual. The bytes are written as they are read, so a body Do reads only partly, like a
+// truncated response, is copied partly too. Errors writing to w are ignored and never fail a request. w is shared
+// by all the requests of the client and must be safe for concurrent use.
+func SetResponseTap(w io.Writer) ClientOpt {
+	return func(c *Client) error {
+		c.responseTap = w
+		return nil
+	}
+}
+
+// tap returns body with its reads copied to the response tap of c, if any.
+func (c *Client) tap(body io.Reader) io.Reader {
+	if c.responseTap == nil {
+		return body
+	}
+
+	return io.TeeReader(body, tapWriter{c.responseTap})
+}
+
+// tapWriter writes to w and reports success whatever w returns, so a failing tap does not fail the read it
+// observes.
+type tapWriter struct {
+	w io.Writer
+}
+
+func (t tapWriter) Write(p []byte) (int, error) {
+	t.w.Write(p)
+	return len(p), nil
+}
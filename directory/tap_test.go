@@ -0,0 +1,55 @@
+package directory
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestSetResponseTap(t *testing.T) {
+	setup()
+	defer teardown()
+
+	const body = `{"id":"erick","fullName":"Erick Guevara"}`
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	})
+
+	var tap bytes.Buffer
+	SetResponseTap(&tap)(client)
+
+	user, _, err := client.Users.Get(ctx, "erick", nil)
+	if err != nil {
+		t.Fatalf("Users.Get() returned error: %v", err)
+	}
+	if user.ID != "erick" || user.FullName != "Erick Guevara" {
+		t.Errorf("Users.Get() returned %+v, expected erick decoded", user)
+	}
+	if tap.String() != body {
+		t.Errorf("tap received %q, expected %q", tap.String(), body)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("tap is down")
+}
+
+func TestSetResponseTap_writeError(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"erick"}`)
+	})
+
+	SetResponseTap(failingWriter{})(client)
+
+	user, _, err := client.Users.Get(ctx, "erick", nil)
+	if err != nil || user.ID != "erick" {
+		t.Errorf("Users.Get() = %+v, %v, expected erick despite the failing tap", user, err)
+	}
+}
@@ -0,0 +1,60 @@
+package directory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Timestamp is a time.Time that decodes from either an RFC 3339 string or a number of seconds since the Unix epoch,
+// as different directory backends send one or the other. An empty string, null or 0 decode to the zero time.
+// Timestamps are encoded as RFC 3339 strings.
+type Timestamp struct {
+	time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "null" || string(data) == `""` {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	if data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %q: %v", s, err)
+		}
+		t.Time = parsed
+		return nil
+	}
+
+	seconds, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %s: %v", data, err)
+	}
+	if seconds == 0 {
+		t.Time = time.Time{}
+		return nil
+	}
+	t.Time = time.Unix(seconds, 0).UTC()
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte(`""`), nil
+	}
+
+	return json.Marshal(t.Format(time.RFC3339))
+}
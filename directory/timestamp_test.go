@@ -0,0 +1,61 @@
+package directory
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimestamp_UnmarshalJSON(t *testing.T) {
+	hired := time.Date(2015, time.March, 2, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		data     string
+		expected time.Time
+	}{
+		{`"2015-03-02T09:30:00Z"`, hired},
+		{`"2015-03-02T04:30:00-05:00"`, hired},
+		{`1425288600`, hired},
+		{`""`, time.Time{}},
+		{`null`, time.Time{}},
+		{`0`, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		var ts Timestamp
+		if err := json.Unmarshal([]byte(tt.data), &ts); err != nil {
+			t.Errorf("Unmarshal(%s) returned error: %v", tt.data, err)
+			continue
+		}
+		if !ts.Equal(tt.expected) {
+			t.Errorf("Unmarshal(%s) = %v, expected %v", tt.data, ts.Time, tt.expected)
+		}
+	}
+
+	for _, data := range []string{`"March 2nd"`, `1.5`, `true`} {
+		var ts Timestamp
+		if err := json.Unmarshal([]byte(data), &ts); err == nil {
+			t.Errorf("Unmarshal(%s) expected error", data)
+		}
+	}
+}
+
+func TestUser_hireDate(t *testing.T) {
+	expected := time.Date(2015, time.March, 2, 9, 30, 0, 0, time.UTC)
+
+	for _, payload := range []string{`{"id":"erick","hireDate":"2015-03-02T09:30:00Z"}`, `{"id":"erick","hire_date":1425288600}`} {
+		u := new(User)
+		if err := json.Unmarshal([]byte(payload), u); err != nil {
+			t.Errorf("Unmarshal(%s) returned error: %v", payload, err)
+			continue
+		}
+		if !u.HireDate.Equal(expected) {
+			t.Errorf("Unmarshal(%s) HireDate = %v, expected %v", payload, u.HireDate, expected)
+		}
+	}
+
+	b, _ := json.Marshal(&User{ID: "erick", HireDate: Timestamp{expected}})
+	if got, want := string(b), `{"coreId":"","fullName":"","status":"","id":"erick","hireDate":"2015-03-02T09:30:00Z"}`; got != want {
+		t.Errorf("Marshal() = %s, expected %s", got, want)
+	}
+}
@@ -0,0 +1,87 @@
+package directory
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Trace holds the phase timings of a request sent with WithTrace. Phases that did not happen, such as DNS and
+// connect on a reused connection, are zero. With retries, each attempt overwrites the timings it records.
+type Trace struct {
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+
+	// Time from writing the request to the first byte of the response.
+	FirstByte time.Duration
+
+	// Time from getting a connection to the first byte of the response.
+	Total time.Duration
+
+	// Whether the request went over a connection reused from a previous request.
+	ConnReused bool
+}
+
+// WithTrace is a request option for recording the DNS, connect, TLS and first byte timings of the request into
+// Response.Trace.
+func WithTrace() RequestOpt {
+	return func(o *requestOptions) error {
+		o.trace = true
+		return nil
+	}
+}
+
+// tracer fills a Trace from httptrace hooks, which may run on other goroutines.
+type tracer struct {
+	mu    sync.Mutex
+	trace Trace
+
+	dnsStart, connectStart, tlsStart, gotConn, wroteRequest time.Time
+}
+
+// result returns a copy of the timings recorded so far.
+func (t *tracer) result() *Trace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	trace := t.trace
+	return &trace
+}
+
+func (t *tracer) clientTrace() *httptrace.ClientTrace {
+	mark := func(start *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		*start = time.Now()
+	}
+	since := func(start *time.Time, d *time.Duration) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if !start.IsZero() {
+			*d = time.Since(*start)
+		}
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { mark(&t.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { since(&t.dnsStart, &t.trace.DNS) },
+		ConnectStart:      func(string, string) { mark(&t.connectStart) },
+		ConnectDone:       func(string, string, error) { since(&t.connectStart, &t.trace.Connect) },
+		TLSHandshakeStart: func() { mark(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { since(&t.tlsStart, &t.trace.TLSHandshake) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			mark(&t.gotConn)
+
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.trace.ConnReused = info.Reused
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) { mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() {
+			since(&t.wroteRequest, &t.trace.FirstByte)
+			since(&t.gotConn, &t.trace.Total)
+		},
+	}
+}
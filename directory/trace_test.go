@@ -0,0 +1,38 @@
+package directory
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestDo_withTrace(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"erick"}`)
+	})
+
+	req, _ := client.NewRequest("GET", "employee/erick", nil, WithTrace())
+	resp, err := client.Do(ctx, req, nil)
+	if err != nil {
+		t.Fatalf("Do() returned error: %v", err)
+	}
+
+	tr := resp.Trace
+	if tr == nil {
+		t.Fatalf("Do() Response.Trace is nil")
+	}
+	if tr.DNS < 0 || tr.Connect < 0 || tr.TLSHandshake < 0 || tr.FirstByte < 0 {
+		t.Errorf("Do() recorded negative timings: %+v", tr)
+	}
+	if tr.Total <= 0 || tr.FirstByte > tr.Total {
+		t.Errorf("Do() recorded inconsistent timings: %+v", tr)
+	}
+
+	req, _ = client.NewRequest("GET", "employee/erick", nil)
+	if resp, _ := client.Do(ctx, req, nil); resp.Trace != nil {
+		t.Errorf("Do() without WithTrace recorded %+v", resp.Trace)
+	}
+}
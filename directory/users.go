@@ -1,16 +1,44 @@
 package directory
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+const ndjsonMediaType = "application/x-ndjson"
+
 // UsersService is an interface for interfacing with the UsersService
 // endpoints of the directory APi.
 //
 // See: https://mm-directory.appspot.com/_ah/api/mm/v1/employee/erick
 type UsersService interface {
-	Get(context.Context, string, *UsersOptions) (*User, *Response, error)
+	Get(context.Context, string, *UsersOptions, ...RequestOpt) (*User, *Response, error)
+	List(context.Context, *UsersListOptions, ...RequestOpt) ([]*User, *Response, error)
+	ListPage(context.Context, *UsersListOptions, ...RequestOpt) (*Page[*User], *Response, error)
+	ListChangedSince(context.Context, time.Time, *UsersListOptions) ([]*User, *Response, error)
+	ListInactive(context.Context, *UsersListOptions) ([]*User, *Response, error)
+	ListAllProgress(context.Context, *UsersListOptions, func(fetched, total int)) ([]*User, *Response, error)
+	ListChan(context.Context, *UsersListOptions) (<-chan *User, <-chan error)
+	Count(context.Context, *UsersListOptions) (int, *Response, error)
+	GetByEmail(context.Context, string, *UsersOptions, ...RequestOpt) (*User, *Response, error)
+	BulkGet(context.Context, []string, *UsersOptions) (map[string]*User, MultiError, error)
+	BulkGetOrdered(context.Context, []string, *UsersOptions) ([]*User, []error, *Response, error)
+	Create(context.Context, *User, ...RequestOpt) (*User, *Response, error)
+	CreateOrGet(context.Context, *User) (*User, bool, *Response, error)
+	StreamCreate(context.Context, <-chan *User) ([]*CreateAck, *Response, error)
+	ManagementChain(context.Context, string) ([]*User, *Response, error)
 }
 
 // UsersServiceOp handles communication with the Users related
@@ -21,12 +49,198 @@ type UsersServiceOp struct {
 
 var _ UsersService = &UsersServiceOp{}
 
+// path returns the URL of the Users endpoint p, relative to the BaseURL, under the prefix set with
+// SetUsersPathPrefix.
+func (u *UsersServiceOp) path(p string) string {
+	if u.client.usersPrefix == "" {
+		return p
+	}
+
+	return u.client.usersPrefix + "/" + p
+}
+
+var (
+	// ErrNotFound is returned when a lookup matches no employee.
+	ErrNotFound = errors.New("employee not found")
+
+	// ErrAmbiguous is returned when a lookup expected to match a single employee matches several.
+	ErrAmbiguous = errors.New("lookup matched more than one employee")
+
+	// ErrCountUnavailable is returned by Count when the list response carries no total.
+	ErrCountUnavailable = errors.New("directory does not report a total count")
+)
+
+// Values of User.Status.
+const (
+	StatusActive   = "A"
+	StatusInactive = "I"
+)
+
 // User represents a directory User resource.
 type User struct {
-	CoreID   string `json:"coreId"`
-	FullName string `json:"fullName"`
-	Status   string `json:"status"`
-	ID       string `json:"id"`
+	CoreID   string    `json:"coreId"`
+	FullName string    `json:"fullName"`
+	Status   string    `json:"status"`
+	ID       string    `json:"id"`
+	Email    string    `json:"email,omitempty"`
+	HireDate Timestamp `json:"hireDate,omitzero"`
+
+	// Manager is set when the API embeds the manager of the user in the response. It is kept shallow: the manager
+	// of the manager is never decoded.
+	Manager *User `json:"manager,omitempty"`
+}
+
+// IsActive reports whether the user has the active status.
+func (u *User) IsActive() bool {
+	return u.Status == StatusActive
+}
+
+// Merge returns a copy of u with the non-zero fields of patch applied, leaving u unchanged. It makes the
+// read-modify-write of an update explicit: fetch the User, merge in only the fields to change, then send the result.
+func (u *User) Merge(patch *User) *User {
+	merged := *u
+	if patch == nil {
+		return &merged
+	}
+
+	dst := reflect.ValueOf(&merged).Elem()
+	src := reflect.ValueOf(patch).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if f := src.Field(i); !f.IsZero() {
+			dst.Field(i).Set(f)
+		}
+	}
+
+	return &merged
+}
+
+// DiffUsers returns the fields of new that differ from old, keyed by their JSON name with their value in new, such
+// as {"status": "I"} for a user who left. The result holds exactly the changes to send in a partial update or to
+// write to an audit log. A nil old is compared as an empty User, so every non-zero field of new is returned; a nil
+// new has no fields to report and yields nil. The result is empty, not nil, when nothing changed.
+func DiffUsers(old, new *User) map[string]interface{} {
+	if new == nil {
+		return nil
+	}
+	if old == nil {
+		old = &User{}
+	}
+
+	diff := make(map[string]interface{})
+	ov := reflect.ValueOf(old).Elem()
+	nv := reflect.ValueOf(new).Elem()
+	for i := 0; i < nv.NumField(); i++ {
+		name, ok := jsonFieldName(nv.Type().Field(i))
+		if !ok {
+			continue
+		}
+		if f := nv.Field(i); !reflect.DeepEqual(ov.Field(i).Interface(), f.Interface()) {
+			diff[name] = f.Interface()
+		}
+	}
+
+	return diff
+}
+
+// UnmarshalJSON decodes a User from either the camelCase field names of the API (coreId, fullName) or their
+// snake_case form (core_id, full_name) returned by some directory endpoints.
+func (u *User) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	// The identifiers accept the numbers some records carry instead of strings.
+	targets := map[string]interface{}{
+		"coreId":   (*flexString)(&u.CoreID),
+		"fullName": &u.FullName,
+		"status":   &u.Status,
+		"id":       (*flexString)(&u.ID),
+		"email":    &u.Email,
+		"hireDate": &u.HireDate,
+		"manager":  &u.Manager,
+	}
+
+	for name, value := range fields {
+		field := userFieldName(name)
+		if _, ok := fields[field]; ok && field != name {
+			// The camelCase form of the field name wins over snake_case forms and aliases.
+			continue
+		}
+
+		target := userFieldTarget(targets, field)
+		if target == nil {
+			continue
+		}
+		if err := json.Unmarshal(value, target); err != nil {
+			if terr, ok := err.(*json.UnmarshalTypeError); ok && terr.Field == "" {
+				terr.Struct, terr.Field = "User", field
+			}
+			return err
+		}
+	}
+
+	if u.Manager != nil {
+		u.Manager.Manager = nil
+	}
+
+	return nil
+}
+
+// userFieldTarget returns the target in targets for the JSON name field, matching case-insensitively like
+// encoding/json does for struct fields, or nil when User has no such field.
+func userFieldTarget(targets map[string]interface{}, field string) interface{} {
+	if target, ok := targets[field]; ok {
+		return target
+	}
+	for name, target := range targets {
+		if strings.EqualFold(name, field) {
+			return target
+		}
+	}
+
+	return nil
+}
+
+// flexString decodes from either a JSON string or a JSON number, keeping the number as written.
+type flexString string
+
+func (s *flexString) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] != '"' {
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+		*s = flexString(n)
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	*s = flexString(str)
+
+	return nil
+}
+
+// camelCase converts a snake_case name such as full_name to camelCase. Other names are returned unchanged.
+func camelCase(name string) string {
+	if !strings.Contains(name, "_") {
+		return name
+	}
+
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+
+	return strings.Join(parts, "")
 }
 
 // UsersOptions specifies the optional parameters to the UserService.Get()
@@ -34,25 +248,798 @@ type UsersOptions struct {
 	Fields *string `url:"fields,omitempty"`
 }
 
+// UsersListOptions specifies the optional parameters to the UserService.List()
+type UsersListOptions struct {
+	// For paginated result sets, page of results to retrieve.
+	Page int `url:"page,omitempty"`
+
+	// Cursor continues a listing from the Response.NextCursor of a previous call.
+	Cursor *string `url:"cursor,omitempty"`
+
+	// Status restricts the results to users with the given status, such as StatusInactive.
+	Status string `url:"status,omitempty"`
+
+	Fields *string `url:"fields,omitempty"`
+}
+
+// usersRoot is the envelope of the employee list returned by the directory API. It also decodes from a bare array
+// of users, which older endpoints return.
+type usersRoot struct {
+	Users      []*User `json:"employees"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+	Total      *int    `json:"total,omitempty"`
+}
+
+func (r *usersRoot) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimLeft(data, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Unmarshal(trimmed, &r.Users)
+	}
+
+	// root has the fields of usersRoot but not its methods, which avoids recursing into UnmarshalJSON.
+	type root usersRoot
+	return json.Unmarshal(data, (*root)(r))
+}
+
+// CreateAck is the server acknowledgment for one user sent by StreamCreate.
+type CreateAck struct {
+	// Line is the 1-based position of the user in the stream.
+	Line  int    `json:"line"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
 // Get will call User service with mmID param.
-func (u *UsersServiceOp) Get(ctx context.Context, mmID string, opt *UsersOptions) (*User, *Response, error) {
+func (u *UsersServiceOp) Get(ctx context.Context, mmID string, opt *UsersOptions, opts ...RequestOpt) (*User, *Response, error) {
+	mmID = strings.TrimSpace(mmID)
 	if mmID == "" {
 		return nil, nil, fmt.Errorf("mmID can not be empty")
 	}
 
-	url := fmt.Sprintf("employee/%v", mmID)
-	url, err := addOptions(url, opt)
+	if opt != nil && u.client.validateFields {
+		if err := validateFields(opt.Fields); err != nil {
+			return nil, nil, err
+		}
+	}
 
-	req, err := u.client.NewRequest("GET", url, nil)
+	// The mmID is one path segment; a "/" or "?" in it must not change the shape of the URL.
+	path := u.path(u.client.usersElement + "/" + url.PathEscape(mmID))
+	url, err := addOptions(path, opt)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	root := new(User)
-	resp, err := u.client.Do(ctx, req, root)
+	ctx = ContextWithLogFields(ctx, map[string]string{"mmID": mmID, "operation": "Users.Get"})
+
+	// Requests with their own options may differ in more than the URL, so they are never shared.
+	if u.client.getFlight != nil && len(opts) == 0 {
+		return u.client.getFlight.do(ctx, url, func(ctx context.Context) (*User, *Response, error) {
+			return u.get(ctx, url, opt)
+		})
+	}
+
+	return u.get(ctx, url, opt, opts...)
+}
+
+// get fetches the user at url.
+func (u *UsersServiceOp) get(ctx context.Context, url string, opt *UsersOptions, opts ...RequestOpt) (*User, *Response, error) {
+	req, err := u.client.NewRequest("GET", url, nil, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decodesIntermediate(req)
+
+	var raw json.RawMessage
+	resp, err := u.client.Do(ctx, req, &raw)
 	if err != nil {
 		return nil, resp, err
 	}
 
+	root, err := u.client.decodeUser(raw)
+	if err != nil {
+		return nil, resp, err
+	}
+	if u.client.rejectEmpty && emptyResource(raw, root) {
+		return nil, resp, ErrEmptyResource
+	}
+
+	if u.client.verifyProjection && opt != nil && opt.Fields != nil {
+		missing, extra := projectionMismatch(raw, *opt.Fields)
+		if len(missing) > 0 {
+			u.client.logf(ctx, "directory: GET %v: response is missing requested fields %v", url, missing)
+		}
+		if len(extra) > 0 {
+			u.client.logf(ctx, "directory: GET %v: response has fields that were not requested %v", url, extra)
+		}
+	}
+
+	if err := u.client.postProcess(root, resp); err != nil {
+		return nil, resp, err
+	}
+
 	return root, resp, err
 }
+
+// projectionMismatch compares the keys of the user object in data, or of the first one when data is an array, with
+// the comma separated fields selection. It returns the requested fields missing from the object and the keys of the
+// object that were not requested.
+func projectionMismatch(data json.RawMessage, fields string) (missing, extra []string) {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var elems []json.RawMessage
+		if err := json.Unmarshal(trimmed, &elems); err != nil || len(elems) == 0 {
+			return nil, nil
+		}
+		trimmed = elems[0]
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &object); err != nil {
+		return nil, nil
+	}
+	present := make(map[string]bool, len(object))
+	for key := range object {
+		present[userFieldName(key)] = true
+	}
+
+	requested := make(map[string]bool)
+	for _, name := range strings.Split(fields, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		requested[name] = true
+		if !present[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(requested) == 0 {
+		// An empty selection asks for every field.
+		return nil, nil
+	}
+	for name := range present {
+		if !requested[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+
+	return missing, extra
+}
+
+// decodeUser decodes a single user with the client's decoder. Some directory instances wrap single lookups in a
+// one-element array, so both a bare object and an array are accepted; for an array the first element is used.
+func (c *Client) decodeUser(data json.RawMessage) (*User, error) {
+	user := new(User)
+
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) == 0 {
+		return user, nil
+	}
+
+	if trimmed[0] == '[' {
+		var users []*User
+		if err := c.decode(trimmed, &users); err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			return nil, errors.New("user response is an empty array")
+		}
+
+		return users[0], nil
+	}
+
+	if err := c.decode(trimmed, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// emailLookupOptions are the query parameters of GetByEmail.
+type emailLookupOptions struct {
+	Email  string  `url:"email"`
+	Fields *string `url:"fields,omitempty"`
+}
+
+// GetByEmail will call User service to find the employee with the given corporate email. ErrNotFound is returned
+// when no employee has that email and ErrAmbiguous when several do.
+func (u *UsersServiceOp) GetByEmail(ctx context.Context, email string, opt *UsersOptions, opts ...RequestOpt) (*User, *Response, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, nil, fmt.Errorf("email can not be empty")
+	}
+
+	lookup := &emailLookupOptions{Email: email}
+	if opt != nil {
+		if u.client.validateFields {
+			if err := validateFields(opt.Fields); err != nil {
+				return nil, nil, err
+			}
+		}
+		lookup.Fields = opt.Fields
+	}
+
+	url, err := addOptions(u.path(u.client.usersCollection), lookup)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := u.client.NewRequest("GET", url, nil, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decodesIntermediate(req)
+
+	root := new(usersRoot)
+	resp, err := u.client.Do(ctx, req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	switch len(root.Users) {
+	case 0:
+		return nil, resp, ErrNotFound
+	case 1:
+		if err := u.client.postProcess(root.Users[0], resp); err != nil {
+			return nil, resp, err
+		}
+		return root.Users[0], resp, nil
+	}
+
+	return nil, resp, ErrAmbiguous
+}
+
+// List will call User service and return a page of users. Use Response.NextCursor
+// as UsersListOptions.Cursor to fetch the following page.
+func (u *UsersServiceOp) List(ctx context.Context, opt *UsersListOptions, opts ...RequestOpt) ([]*User, *Response, error) {
+	if opt != nil && u.client.validateFields {
+		if err := validateFields(opt.Fields); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	url, err := addOptions(u.path(u.client.usersCollection), opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return u.list(ctx, url, opts...)
+}
+
+// ListPage is List returning the page of users as a Page, with the cursor of the following page and the total
+// number of users when the API reports it.
+func (u *UsersServiceOp) ListPage(ctx context.Context, opt *UsersListOptions, opts ...RequestOpt) (*Page[*User], *Response, error) {
+	users, resp, err := u.List(ctx, opt, opts...)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return newPage(users, resp), resp, nil
+}
+
+// ListInactive will call User service and return a page of the inactive users, for offboarding audits. It pages
+// like List; the Status of opt is ignored. Users the directory returns despite the filter that are active are left
+// out.
+func (u *UsersServiceOp) ListInactive(ctx context.Context, opt *UsersListOptions) ([]*User, *Response, error) {
+	filtered := UsersListOptions{}
+	if opt != nil {
+		filtered = *opt
+	}
+	filtered.Status = StatusInactive
+
+	users, resp, err := u.List(ctx, &filtered)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	inactive := users[:0]
+	for _, user := range users {
+		if !user.IsActive() {
+			inactive = append(inactive, user)
+		}
+	}
+
+	return inactive, resp, nil
+}
+
+// ListAllProgress fetches every page of the users matching opt, following the pages like UserIterator, and
+// returns all of them with the response of the last page. After each page progress, when not nil, is called with
+// the number of users fetched so far and the total reported by the pagination metadata, or -1 when the API does
+// not report one. On error the users fetched before the failing page are returned.
+func (u *UsersServiceOp) ListAllProgress(ctx context.Context, opt *UsersListOptions, progress func(fetched, total int)) ([]*User, *Response, error) {
+	it := NewUserIterator(u, opt)
+
+	var (
+		users []*User
+		resp  *Response
+		err   error
+	)
+	for !it.done {
+		resp, err = it.fetch(ctx)
+		if err != nil {
+			return users, resp, err
+		}
+		users = append(users, it.page...)
+		it.page = nil
+
+		if progress != nil {
+			total := -1
+			if resp.Total != nil {
+				total = *resp.Total
+			}
+			progress(len(users), total)
+		}
+	}
+
+	return users, resp, nil
+}
+
+// ListChan pages through the users matching opt in a new goroutine, like UserIterator, and sends them on the
+// returned users channel as each page is decoded. The users channel is closed once every user has been sent or on
+// the first error, which is then sent on the error channel; the error channel is closed after the users channel.
+// If ctx is done, paging stops and ctx.Err() is sent on the error channel. The caller must drain the users channel
+// or cancel ctx so the goroutine can exit.
+func (u *UsersServiceOp) ListChan(ctx context.Context, opt *UsersListOptions) (<-chan *User, <-chan error) {
+	users := make(chan *User)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(users)
+
+		it := NewUserIterator(u, opt)
+		for {
+			user, err := it.Next(ctx)
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				errc <- err
+				return
+			}
+
+			select {
+			case users <- user:
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return users, errc
+}
+
+// ListChangedSince will call User service and return a page of the users modified after since, for incremental
+// syncs. It pages like List: use Response.NextCursor as UsersListOptions.Cursor to fetch the rest of the delta.
+func (u *UsersServiceOp) ListChangedSince(ctx context.Context, since time.Time, opt *UsersListOptions) ([]*User, *Response, error) {
+	if opt != nil && u.client.validateFields {
+		if err := validateFields(opt.Fields); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	s, err := addOptions(u.path(u.client.usersCollection), opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rel, err := url.Parse(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	q := rel.Query()
+	q.Set("modifiedSince", since.UTC().Format(time.RFC3339))
+	rel.RawQuery = q.Encode()
+
+	return u.list(ctx, rel.String())
+}
+
+// list fetches the page of users at url.
+func (u *UsersServiceOp) list(ctx context.Context, url string, opts ...RequestOpt) ([]*User, *Response, error) {
+	req, err := u.client.NewRequest("GET", url, nil, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decodesIntermediate(req)
+
+	root := new(usersRoot)
+	resp, err := u.client.Do(ctx, req, root)
+	if err != nil {
+		return nil, resp, err
+	}
+	resp.NextCursor = root.NextCursor
+	resp.Total = root.Total
+	if err := u.client.postProcess(root.Users, resp); err != nil {
+		return nil, resp, err
+	}
+
+	return root.Users, resp, err
+}
+
+// Count returns the total number of users matching opt, read from the X-Total-Count header of a list response or
+// the total of its pagination metadata. ErrCountUnavailable is returned when the response carries neither.
+func (u *UsersServiceOp) Count(ctx context.Context, opt *UsersListOptions) (int, *Response, error) {
+	url, err := addOptions(u.path(u.client.usersCollection), opt)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req, err := u.client.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	decodesIntermediate(req)
+
+	root := new(usersRoot)
+	resp, err := u.client.Do(ctx, req, root)
+	if err != nil {
+		return 0, resp, err
+	}
+
+	if h := resp.Header.Get("X-Total-Count"); h != "" {
+		total, err := strconv.Atoi(h)
+		if err != nil || total < 0 {
+			return 0, resp, fmt.Errorf("invalid X-Total-Count header %q", h)
+		}
+		return total, resp, nil
+	}
+	if root.Total != nil {
+		return *root.Total, resp, nil
+	}
+
+	return 0, resp, ErrCountUnavailable
+}
+
+// BulkGet calls Get concurrently for every mmID and returns the users found keyed by mmID. Calls that failed
+// are reported per mmID in the returned MultiError, which is nil when every call succeeded.
+//
+// When ctx has a deadline, each call gets its own share of the remaining time based on the number of calls still
+// pending, so a slow call can not starve the rest of the batch.
+//
+// If ctx is cancelled or its deadline passes, BulkGet stops starting new calls, waits for the ones in flight and
+// returns the partial results fetched so far together with ctx.Err(). mmIDs that were never requested appear in
+// neither the users nor the errors.
+func (u *UsersServiceOp) BulkGet(ctx context.Context, mmIDs []string, opt *UsersOptions) (map[string]*User, MultiError, error) {
+	users := make(map[string]*User, len(mmIDs))
+	errs := make(MultiError)
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	concurrency := u.client.bulkConcurrency
+	sem := make(chan struct{}, concurrency)
+
+	ids := uniqueIDs(mmIDs)
+dispatch:
+	for i, id := range ids {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break dispatch
+		}
+		if ctx.Err() != nil {
+			// select picks randomly when both cases are ready.
+			<-sem
+			break dispatch
+		}
+
+		reqCtx, cancel := budgetContext(ctx, len(ids)-i, concurrency, u.client.perRequestTimeout)
+		wg.Add(1)
+		go func(id string) {
+			defer func() {
+				cancel()
+				<-sem
+				wg.Done()
+			}()
+
+			user, _, err := u.Get(reqCtx, id, opt)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[id] = err
+				return
+			}
+			users[id] = user
+		}(id)
+	}
+	wg.Wait()
+
+	if !errs.Any() {
+		errs = nil
+	}
+
+	return users, errs, ctx.Err()
+}
+
+// BulkGetOrdered is BulkGet with its results aligned to mmIDs: the user and the error at index i belong to mmIDs[i],
+// so exactly one of them is nil when the call for mmIDs[i] ran. mmIDs that were never requested because ctx was
+// done get ctx.Err(). The *Response is always nil, the results come from one response per mmID.
+func (u *UsersServiceOp) BulkGetOrdered(ctx context.Context, mmIDs []string, opt *UsersOptions) ([]*User, []error, *Response, error) {
+	found, failed, err := u.BulkGet(ctx, mmIDs, opt)
+
+	users := make([]*User, len(mmIDs))
+	errs := make([]error, len(mmIDs))
+	for i, id := range mmIDs {
+		switch user, ok := found[id]; {
+		case ok:
+			users[i] = user
+		case failed[id] != nil:
+			errs[i] = failed[id]
+		default:
+			errs[i] = err
+		}
+	}
+
+	return users, errs, nil, err
+}
+
+// budgetContext derives a context for one of pending calls run with the given concurrency. If ctx has a deadline,
+// the remaining time is split evenly across the rounds needed to run the pending calls. A positive limit caps the
+// time given to the call.
+func budgetContext(ctx context.Context, pending, concurrency int, limit time.Duration) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		if limit > 0 {
+			return context.WithTimeout(ctx, limit)
+		}
+		return context.WithCancel(ctx)
+	}
+
+	rounds := (pending + concurrency - 1) / concurrency
+	budget := time.Until(deadline) / time.Duration(rounds)
+	if limit > 0 && limit < budget {
+		budget = limit
+	}
+
+	return context.WithTimeout(ctx, budget)
+}
+
+// uniqueIDs returns ids without duplicates, keeping the first occurrence order.
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+
+	return unique
+}
+
+// Create will call User service to create the given employee and returns the user stored by the directory. When
+// sent WithPrefer(PreferMinimal) the directory may answer without a body, typically 204 No Content; user itself is
+// then returned.
+func (u *UsersServiceOp) Create(ctx context.Context, user *User, opts ...RequestOpt) (*User, *Response, error) {
+	if user == nil {
+		return nil, nil, fmt.Errorf("user can not be nil")
+	}
+
+	req, err := u.client.NewRequest("POST", u.path(u.client.usersCollection), user, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decodesIntermediate(req)
+
+	created := new(User)
+	resp, err := u.client.Do(ctx, req, created)
+	if err != nil {
+		return nil, resp, err
+	}
+	if u.client.rejectEmpty && emptyResource(resp.body, created) {
+		return nil, resp, ErrEmptyResource
+	}
+
+	entry := AuditEntry{Operation: AuditCreate, ID: created.ID, Payload: user, After: created}
+	if prefersMinimal(req) && (resp.StatusCode == http.StatusNoContent || len(resp.body) == 0) {
+		created = user
+		entry.After = nil
+	}
+	if entry.ID == "" {
+		entry.ID = user.ID
+	}
+	u.client.audit(entry)
+
+	if err := u.client.postProcess(created, resp); err != nil {
+		return nil, resp, err
+	}
+
+	return created, resp, nil
+}
+
+// CreateOrGet creates user like Create, but when the directory reports that the employee already exists it fetches
+// and returns the existing record instead of failing. The returned bool is true when the user was created and false
+// when the existing one was fetched.
+func (u *UsersServiceOp) CreateOrGet(ctx context.Context, user *User) (*User, bool, *Response, error) {
+	created, resp, err := u.Create(ctx, user)
+	if err == nil {
+		return created, true, resp, nil
+	}
+	if !alreadyExists(err) {
+		return nil, false, resp, err
+	}
+
+	existing, resp, err := u.Get(ctx, user.ID, nil)
+	if err != nil {
+		return nil, false, resp, err
+	}
+
+	return existing, false, resp, nil
+}
+
+// alreadyExists reports whether err is the directory rejecting a create for an employee that already exists, either
+// with a 409 Conflict or an error envelope with the alreadyExists reason.
+func alreadyExists(err error) bool {
+	errResp, ok := err.(*ErrorResponse)
+	if !ok {
+		return false
+	}
+	if errResp.Response != nil && errResp.Response.StatusCode == http.StatusConflict {
+		return true
+	}
+
+	return errResp.HasReason(ReasonAlreadyExists)
+}
+
+// StreamCreate creates every user received from users by streaming them to the bulk endpoint as newline-delimited
+// JSON while they are encoded, instead of buffering one large array. The stream ends when users is closed or ctx
+// is done. The server acknowledges every line; the acknowledgments are returned in the order received.
+//
+// StreamCreate stops receiving from users before it returns, including when the request fails before the stream
+// starts, so the caller must stop producing once it returns: a user sent afterwards is never received.
+func (u *UsersServiceOp) StreamCreate(ctx context.Context, users <-chan *User) ([]*CreateAck, *Response, error) {
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	defer func() {
+		// Closing the read side unblocks the encoder if the request ends early.
+		close(done)
+		pr.Close()
+		<-stopped
+	}()
+
+	go func() {
+		defer close(stopped)
+
+		enc := json.NewEncoder(pw)
+		for {
+			select {
+			case <-done:
+				pw.Close()
+				return
+			case <-ctx.Done():
+				pw.CloseWithError(ctx.Err())
+				return
+			case user, ok := <-users:
+				if !ok {
+					pw.Close()
+					return
+				}
+				if err := enc.Encode(user); err != nil {
+					pw.CloseWithError(err)
+					return
+				}
+			}
+		}
+	}()
+
+	req, err := u.client.NewRequestRaw("POST", u.path(u.client.usersCollection+"/bulk"), pr, ndjsonMediaType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body := new(bytes.Buffer)
+	resp, err := u.client.Do(ctx, req, body)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var acks []*CreateAck
+	dec := json.NewDecoder(body)
+	for {
+		ack := new(CreateAck)
+		if err := dec.Decode(ack); err == io.EOF {
+			break
+		} else if err != nil {
+			return acks, resp, err
+		}
+		acks = append(acks, ack)
+		if ack.Error == "" {
+			u.client.audit(AuditEntry{Operation: AuditStreamCreate, ID: ack.ID})
+		}
+	}
+
+	return acks, resp, nil
+}
+
+// userFields is the set of JSON field names of User.
+var userFields = jsonFieldNames(reflect.TypeOf(User{}))
+
+// jsonFieldNames returns the JSON names of the exported fields of the struct type t.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	list := jsonFieldList(t)
+	names := make(map[string]bool, len(list))
+	for _, name := range list {
+		names[name] = true
+	}
+
+	return names
+}
+
+// jsonFieldList returns the JSON names of the exported fields of the struct type t in declaration order.
+func jsonFieldList(t reflect.Type) []string {
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := jsonFieldName(t.Field(i)); ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// jsonFieldName returns the JSON name of the struct field f, reporting false when encoding/json skips it.
+func jsonFieldName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = f.Name
+	}
+
+	return name, true
+}
+
+// FieldsFromStruct returns the JSON names of the exported fields of the struct, or pointer to struct, v in
+// declaration order, so a local struct describing the User fields it consumes can drive the fields selection:
+//
+//	fields := strings.Join(directory.FieldsFromStruct(badge{}), ",")
+//	user, _, err := client.Users.Get(ctx, "erick", &directory.UsersOptions{Fields: &fields})
+//
+// Fields tagged json:"-" are skipped and untagged fields use the Go field name. It returns nil when v is not a
+// struct.
+func FieldsFromStruct(v interface{}) []string {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return jsonFieldList(t)
+}
+
+// validateFields checks that every name in the comma separated fields selection is a known User field.
+func validateFields(fields *string) error {
+	if fields == nil {
+		return nil
+	}
+
+	for _, name := range strings.Split(*fields, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !userFields[name] {
+			return fmt.Errorf("unknown User field %q in fields", name)
+		}
+	}
+
+	return nil
+}
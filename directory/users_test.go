@@ -1,11 +1,15 @@
 package directory
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
 	"testing"
+	"time"
 )
 
 // User used to call User endpoint.
@@ -92,6 +96,35 @@ func TestUsers_Get_emptyUser(t *testing.T) {
 	}
 }
 
+func TestUsers_Get_whitespaceUser(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Get() sent a request to %v for a blank mmID", r.URL)
+	})
+
+	for _, id := range []string{" ", "  ", "\t", " \t\n"} {
+		_, _, err := client.Users.Get(ctx, id, nil)
+		if err == nil || err.Error() != "mmID can not be empty" {
+			t.Errorf("Get(%q) expected empty mmID error, got %v", id, err)
+		}
+	}
+}
+
+func TestUsers_Get_trimsUser(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, userJSON)
+	})
+
+	if _, _, err := client.Users.Get(ctx, " erick\t", nil); err != nil {
+		t.Errorf("Get() returned error: %v", err)
+	}
+}
+
 func TestUsers_Get_badBody(t *testing.T) {
 	setup()
 	defer teardown()
@@ -136,4 +169,1097 @@ func TestUsers_Get_employeeDoesNotExist(t *testing.T) {
 
 	}
 
+	errResp, ok := err.(*ErrorResponse)
+	if !ok {
+		t.Fatalf("Get() error = %T, expected *ErrorResponse", err)
+	}
+	if !errResp.HasReason(ReasonBadRequest) {
+		t.Errorf("HasReason(%q) = false, expected true", ReasonBadRequest)
+	}
+	if errResp.HasReason(ReasonNotFound) {
+		t.Errorf("HasReason(%q) = true, expected false", ReasonNotFound)
+	}
+	if got := errResp.Errors[0].Domain; got != DomainGlobal {
+		t.Errorf("Errors[0].Domain = %q, expected %q", got, DomainGlobal)
+	}
+
+}
+
+func TestUsers_List_cursor(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var cursors []string
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		cursor := r.URL.Query().Get("cursor")
+		cursors = append(cursors, cursor)
+
+		switch cursor {
+		case "":
+			fmt.Fprint(w, `{"employees":[{"id":"mmid1"}],"nextCursor":"page2"}`)
+		case "page2":
+			fmt.Fprint(w, `{"employees":[{"id":"mmid2"}]}`)
+		default:
+			t.Errorf("List() unexpected cursor %q", cursor)
+		}
+	})
+
+	users, resp, err := client.Users.List(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("List() returned error: %v", err)
+	}
+	if got, want := resp.NextCursor, "page2"; got != want {
+		t.Errorf("List() NextCursor = %q, want %q", got, want)
+	}
+	if got, want := users, []*User{{ID: "mmid1"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() returned %+v, expected %+v", got, want)
+	}
+
+	opt := &UsersListOptions{Cursor: &resp.NextCursor}
+	users, resp, err = client.Users.List(context.Background(), opt)
+	if err != nil {
+		t.Fatalf("List() returned error: %v", err)
+	}
+	if resp.NextCursor != "" {
+		t.Errorf("List() NextCursor = %q, expected end of results", resp.NextCursor)
+	}
+	if got, want := users, []*User{{ID: "mmid2"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() returned %+v, expected %+v", got, want)
+	}
+
+	if got, want := cursors, []string{"", "page2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() sent cursors %v, expected %v", got, want)
+	}
+}
+
+func TestUsers_BulkGet(t *testing.T) {
+	setup()
+	defer teardown()
+
+	for _, id := range []string{"a", "b"} {
+		id := id
+		mux.HandleFunc("/employee/"+id, func(w http.ResponseWriter, r *http.Request) {
+			testMethod(t, r, "GET")
+			fmt.Fprintf(w, `{"id":%q}`, id)
+		})
+	}
+
+	users, errs, err := client.Users.BulkGet(ctx, []string{"a", "b", "a", "missing"}, nil)
+	if err != nil {
+		t.Errorf("BulkGet() returned error: %v", err)
+	}
+
+	expected := map[string]*User{"a": {ID: "a"}, "b": {ID: "b"}}
+	if !reflect.DeepEqual(users, expected) {
+		t.Errorf("BulkGet() returned %+v, expected %+v", users, expected)
+	}
+	if len(errs) != 1 || errs["missing"] == nil {
+		t.Errorf("BulkGet() errors = %v, expected only missing to fail", errs)
+	}
+}
+
+func TestUsers_BulkGet_deadlineBudget(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.bulkConcurrency = 1
+
+	mux.HandleFunc("/employee/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	for _, id := range []string{"a", "b"} {
+		id := id
+		mux.HandleFunc("/employee/"+id, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"id":%q}`, id)
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	users, errs, _ := client.Users.BulkGet(ctx, []string{"slow", "a", "b"}, nil)
+
+	if len(users) != 2 || users["a"] == nil || users["b"] == nil {
+		t.Errorf("BulkGet() returned %+v, expected a and b to complete", users)
+	}
+	if err := errs["slow"]; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("BulkGet() slow error = %v, expected %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestUsers_Get_acceptLanguage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var lang string
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		lang = r.Header.Get("Accept-Language")
+		fmt.Fprint(w, userJSON)
+	})
+
+	if err := SetAcceptLanguage("es-MX")(client); err != nil {
+		t.Fatalf("SetAcceptLanguage() unexpected error: %v", err)
+	}
+
+	if _, _, err := client.Users.Get(ctx, user, nil); err != nil {
+		t.Fatalf("Get() returned error: %v", err)
+	}
+	if got, want := lang, "es-MX"; got != want {
+		t.Errorf("Get() Accept-Language = %q, expected %q", got, want)
+	}
+
+	if _, _, err := client.Users.Get(ctx, user, nil, WithAcceptLanguage("fr")); err != nil {
+		t.Fatalf("Get() returned error: %v", err)
+	}
+	if got, want := lang, "fr"; got != want {
+		t.Errorf("Get() overridden Accept-Language = %q, expected %q", got, want)
+	}
+}
+
+func TestUsers_StreamCreate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/bulk", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		if got, want := r.Header.Get("Content-Type"), "application/x-ndjson"; got != want {
+			t.Errorf("StreamCreate() Content-Type = %q, expected %q", got, want)
+		}
+
+		scanner := bufio.NewScanner(r.Body)
+		for line := 1; scanner.Scan(); line++ {
+			u := new(User)
+			if err := json.Unmarshal(scanner.Bytes(), u); err != nil {
+				t.Errorf("StreamCreate() line %d is not JSON: %q", line, scanner.Text())
+				continue
+			}
+			fmt.Fprintf(w, `{"line":%d,"id":%q}`+"\n", line, u.ID)
+		}
+	})
+
+	users := make(chan *User)
+	go func() {
+		for _, id := range []string{"a", "b", "c"} {
+			users <- &User{ID: id}
+		}
+		close(users)
+	}()
+
+	acks, _, err := client.Users.StreamCreate(ctx, users)
+	if err != nil {
+		t.Fatalf("StreamCreate() returned error: %v", err)
+	}
+
+	expected := []*CreateAck{{Line: 1, ID: "a"}, {Line: 2, ID: "b"}, {Line: 3, ID: "c"}}
+	if !reflect.DeepEqual(acks, expected) {
+		t.Errorf("StreamCreate() returned %+v, expected %+v", acks, expected)
+	}
+}
+
+func TestUsers_StreamCreate_requestFails(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.CancelAll()
+
+	users := make(chan *User)
+	if _, _, err := client.Users.StreamCreate(ctx, users); err != ErrClientCancelled {
+		t.Fatalf("StreamCreate() error = %v, expected %v", err, ErrClientCancelled)
+	}
+
+	// The encoder has stopped: nothing receives from users anymore.
+	select {
+	case users <- &User{ID: "lost"}:
+		t.Errorf("StreamCreate() still received users after returning")
+	default:
+	}
+}
+
+func TestUsers_BulkGet_cancelled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.bulkConcurrency = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, id := range []string{"a", "b", "c"} {
+		id := id
+		mux.HandleFunc("/employee/"+id, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"id":%q}`, id)
+		})
+	}
+	mux.HandleFunc("/employee/block", func(w http.ResponseWriter, r *http.Request) {
+		// a and b have been fetched; the caller gives up while this one is in flight.
+		cancel()
+		<-r.Context().Done()
+	})
+
+	users, errs, err := client.Users.BulkGet(ctx, []string{"a", "b", "block", "c"}, nil)
+
+	if err != context.Canceled {
+		t.Errorf("BulkGet() error = %v, expected %v", err, context.Canceled)
+	}
+	expected := map[string]*User{"a": {ID: "a"}, "b": {ID: "b"}}
+	if !reflect.DeepEqual(users, expected) {
+		t.Errorf("BulkGet() partial results = %+v, expected %+v", users, expected)
+	}
+	if errs["block"] == nil {
+		t.Errorf("BulkGet() expected the in-flight call to fail")
+	}
+	if _, ok := errs["c"]; ok {
+		t.Errorf("BulkGet() reported an error for an mmID that was never requested")
+	}
+}
+
+func TestUsers_Get_shapes(t *testing.T) {
+	expected := &User{CoreID: "aeg095", FullName: "Erick Guevara", Status: "A", ID: "erick"}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"object", userJSON},
+		{"single element array", "[" + userJSON + "]"},
+	}
+
+	for _, tt := range tests {
+		setup()
+
+		mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, tt.body)
+		})
+
+		got, _, err := client.Users.Get(ctx, user, nil)
+		if err != nil {
+			t.Errorf("Get() %v returned error: %v", tt.name, err)
+		} else if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Get() %v returned %+v, expected %+v", tt.name, got, expected)
+		}
+
+		teardown()
+	}
+}
+
+func TestUsers_Get_emptyArray(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, listEmptyJSON)
+	})
+
+	if _, _, err := client.Users.Get(ctx, user, nil); err == nil {
+		t.Errorf("Get() expected error for an empty array")
+	}
+}
+
+func TestUsers_Get_validateFields(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, userJSON)
+	})
+
+	fields := "coreId, fulName"
+	opt := &UsersOptions{Fields: &fields}
+
+	// Validation is off by default.
+	if _, _, err := client.Users.Get(ctx, user, opt); err != nil {
+		t.Errorf("Get() returned error: %v", err)
+	}
+
+	SetValidateFields(true)(client)
+
+	_, _, err := client.Users.Get(ctx, user, opt)
+	if err == nil || err.Error() != `unknown User field "fulName" in fields` {
+		t.Errorf("Get() expected unknown field error, got %v", err)
+	}
+
+	_, _, err = client.Users.List(ctx, &UsersListOptions{Fields: &fields})
+	if err == nil {
+		t.Errorf("List() expected unknown field error")
+	}
+
+	fields = "coreId,fullName,status,id"
+	if _, _, err := client.Users.Get(ctx, user, opt); err != nil {
+		t.Errorf("Get() returned error for known fields: %v", err)
+	}
+}
+
+func TestUser_UnmarshalJSON_casing(t *testing.T) {
+	expected := &User{CoreID: "aeg095", FullName: "Erick Guevara", Status: "A", ID: "erick"}
+
+	tests := map[string]string{
+		"camelCase":  `{"coreId":"aeg095","fullName":"Erick Guevara","status":"A","id":"erick"}`,
+		"snake_case": `{"core_id":"aeg095","full_name":"Erick Guevara","status":"A","id":"erick"}`,
+		"both":       `{"core_id":"other","coreId":"aeg095","fullName":"Erick Guevara","status":"A","id":"erick"}`,
+	}
+
+	for name, payload := range tests {
+		got := new(User)
+		if err := json.Unmarshal([]byte(payload), got); err != nil {
+			t.Errorf("Unmarshal() %v returned error: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Unmarshal() %v = %+v, expected %+v", name, got, expected)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`["not","a","user"]`), new(User)); err == nil {
+		t.Errorf("Unmarshal() expected error for a non-object payload")
+	}
+}
+
+func TestUser_UnmarshalJSON_typeError(t *testing.T) {
+	err := json.Unmarshal([]byte(`{"full_name":7}`), new(User))
+	terr, ok := err.(*json.UnmarshalTypeError)
+	if !ok || terr.Field != "fullName" {
+		t.Errorf("Unmarshal() error = %#v, expected a type error for fullName", err)
+	}
+
+	// A null identifier leaves the field alone, as it does for the other fields.
+	got := &User{ID: "erick"}
+	if err := json.Unmarshal([]byte(`{"id":null}`), got); err != nil || got.ID != "erick" {
+		t.Errorf("Unmarshal() null id = %+v, %v, expected erick kept", got, err)
+	}
+}
+
+func TestUsers_GetByEmail(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"email": "erick@example.com", "fields": "id,email"})
+		fmt.Fprint(w, `{"employees":[{"id":"erick","email":"erick@example.com"}]}`)
+	})
+
+	fields := "id,email"
+	got, _, err := client.Users.GetByEmail(ctx, "erick@example.com", &UsersOptions{Fields: &fields})
+	if err != nil {
+		t.Fatalf("GetByEmail() returned error: %v", err)
+	}
+
+	expected := &User{ID: "erick", Email: "erick@example.com"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("GetByEmail() returned %+v, expected %+v", got, expected)
+	}
+}
+
+func TestUsers_GetByEmail_notFoundAndAmbiguous(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("email") {
+		case "shared@example.com":
+			fmt.Fprint(w, `{"employees":[{"id":"a"},{"id":"b"}]}`)
+		default:
+			fmt.Fprint(w, `{"employees":[]}`)
+		}
+	})
+
+	if _, _, err := client.Users.GetByEmail(ctx, "nobody@example.com", nil); err != ErrNotFound {
+		t.Errorf("GetByEmail() error = %v, expected %v", err, ErrNotFound)
+	}
+	if _, _, err := client.Users.GetByEmail(ctx, "shared@example.com", nil); err != ErrAmbiguous {
+		t.Errorf("GetByEmail() error = %v, expected %v", err, ErrAmbiguous)
+	}
+	if _, _, err := client.Users.GetByEmail(ctx, " ", nil); err == nil {
+		t.Errorf("GetByEmail() expected error for an empty email")
+	}
+}
+
+func TestUser_email(t *testing.T) {
+	u := new(User)
+	if err := json.Unmarshal([]byte(`{"id":"erick","email":"erick@example.com"}`), u); err != nil {
+		t.Fatalf("Unmarshal() returned error: %v", err)
+	}
+	if got, want := u.Email, "erick@example.com"; got != want {
+		t.Errorf("User.Email = %q, expected %q", got, want)
+	}
+
+	b, _ := json.Marshal(&User{ID: "erick"})
+	if got, want := string(b), `{"coreId":"","fullName":"","status":"","id":"erick"}`; got != want {
+		t.Errorf("Marshal() = %s, expected empty email to be omitted", got)
+	}
+}
+
+func TestUsers_Get_errorMessage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/employee_does_not_exist", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, employeeDoesNotExist)
+	})
+
+	_, _, err := client.Users.Get(ctx, "employee_does_not_exist", nil)
+	if err == nil {
+		t.Fatalf("Get() expected error")
+	}
+
+	expected := "GET /employee/employee_does_not_exist: Employee does not exists."
+	if got := err.Error(); got != expected {
+		t.Errorf("Get() error = %q, expected %q", got, expected)
+	}
+}
+
+func TestUser_Merge(t *testing.T) {
+	u := &User{CoreID: "1234", FullName: "Erick Guevara", Status: "A", ID: "erick", Email: "erick@example.com"}
+
+	merged := u.Merge(&User{Status: "I"})
+
+	expected := &User{CoreID: "1234", FullName: "Erick Guevara", Status: "I", ID: "erick", Email: "erick@example.com"}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Merge() returned %+v, expected %+v", merged, expected)
+	}
+	if u.Status != "A" {
+		t.Errorf("Merge() modified the receiver, Status = %q", u.Status)
+	}
+	if got := u.Merge(nil); got == u || !reflect.DeepEqual(got, u) {
+		t.Errorf("Merge(nil) returned %+v, expected a copy of %+v", got, u)
+	}
+}
+
+func TestUser_manager(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"erick","manager":{"id":"andrew","full_name":"Andrew Smith","manager":{"id":"tom"}}}`)
+	})
+
+	user, _, err := client.Users.Get(ctx, "erick", nil)
+	if err != nil {
+		t.Fatalf("Users.Get() returned error: %v", err)
+	}
+
+	expected := &User{ID: "erick", Manager: &User{ID: "andrew", FullName: "Andrew Smith"}}
+	if !reflect.DeepEqual(user, expected) {
+		t.Errorf("Users.Get() returned %+v, expected %+v", user, expected)
+	}
+}
+
+func TestUsers_BulkGet_perRequestTimeout(t *testing.T) {
+	setup()
+	defer teardown()
+
+	SetPerRequestTimeout(50 * time.Millisecond)(client)
+
+	mux.HandleFunc("/employee/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	for _, id := range []string{"a", "b", "c"} {
+		id := id
+		mux.HandleFunc("/employee/"+id, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"id":%q}`, id)
+		})
+	}
+
+	users, errs, err := client.Users.BulkGet(context.Background(), []string{"a", "slow", "b", "c"}, nil)
+	if err != nil {
+		t.Errorf("BulkGet() returned error: %v", err)
+	}
+
+	expected := map[string]*User{"a": {ID: "a"}, "b": {ID: "b"}, "c": {ID: "c"}}
+	if !reflect.DeepEqual(users, expected) {
+		t.Errorf("BulkGet() returned %+v, expected %+v", users, expected)
+	}
+	if len(errs) != 1 || !errors.Is(errs["slow"], context.DeadlineExceeded) {
+		t.Errorf("BulkGet() errors = %v, expected only slow to time out", errs)
+	}
+}
+
+func TestUsers_Count(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		switch r.FormValue("page") {
+		case "":
+			w.Header().Set("X-Total-Count", "1234")
+			fmt.Fprint(w, `{"employees":[{"id":"erick"}]}`)
+		case "2":
+			fmt.Fprint(w, `{"employees":[],"total":56}`)
+		default:
+			fmt.Fprint(w, `{"employees":[]}`)
+		}
+	})
+
+	count, _, err := client.Users.Count(ctx, nil)
+	if err != nil {
+		t.Fatalf("Count() returned error: %v", err)
+	}
+	if count != 1234 {
+		t.Errorf("Count() returned %d, expected 1234", count)
+	}
+
+	if count, _, _ := client.Users.Count(ctx, &UsersListOptions{Page: 2}); count != 56 {
+		t.Errorf("Count() from the list total returned %d, expected 56", count)
+	}
+
+	if _, _, err := client.Users.Count(ctx, &UsersListOptions{Page: 3}); err != ErrCountUnavailable {
+		t.Errorf("Count() error = %v, expected %v", err, ErrCountUnavailable)
+	}
+}
+
+func TestUser_numericIDs(t *testing.T) {
+	tests := []struct {
+		payload  string
+		expected *User
+	}{
+		{`{"id":"erick","coreId":"aeg095"}`, &User{ID: "erick", CoreID: "aeg095"}},
+		{`{"id":12345,"core_id":987}`, &User{ID: "12345", CoreID: "987"}},
+		{`{"id":null}`, &User{}},
+	}
+
+	for _, tt := range tests {
+		u := new(User)
+		if err := json.Unmarshal([]byte(tt.payload), u); err != nil {
+			t.Errorf("Unmarshal(%s) returned error: %v", tt.payload, err)
+			continue
+		}
+		if !reflect.DeepEqual(u, tt.expected) {
+			t.Errorf("Unmarshal(%s) = %+v, expected %+v", tt.payload, u, tt.expected)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"id":true}`), new(User)); err == nil {
+		t.Errorf("Unmarshal() expected error for a boolean id")
+	}
+}
+
+func TestUsers_Create(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		v := new(User)
+		json.NewDecoder(r.Body).Decode(v)
+		if v.ID != "erick" {
+			t.Errorf("Request body id = %q, expected erick", v.ID)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"erick","coreId":"aeg095"}`)
+	})
+
+	user, _, err := client.Users.Create(ctx, &User{ID: "erick"})
+	if err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+
+	expected := &User{ID: "erick", CoreID: "aeg095"}
+	if !reflect.DeepEqual(user, expected) {
+		t.Errorf("Create() returned %+v, expected %+v", user, expected)
+	}
+}
+
+func TestUsers_Create_prefer(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		switch r.Header.Get("Prefer") {
+		case PreferMinimal:
+			w.WriteHeader(http.StatusNoContent)
+		case PreferRepresentation:
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":"erick","coreId":"aeg095"}`)
+		default:
+			t.Errorf("Prefer header = %q, expected a return preference", r.Header.Get("Prefer"))
+		}
+	})
+
+	input := &User{ID: "erick"}
+	user, resp, err := client.Users.Create(ctx, input, WithPrefer(PreferMinimal))
+	if err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	if resp.StatusCode != http.StatusNoContent || user != input {
+		t.Errorf("Create() minimal returned %+v with status %d, expected the input user", user, resp.StatusCode)
+	}
+
+	user, _, err = client.Users.Create(ctx, input, WithPrefer(PreferRepresentation))
+	if err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	if expected := (&User{ID: "erick", CoreID: "aeg095"}); !reflect.DeepEqual(user, expected) {
+		t.Errorf("Create() representation returned %+v, expected %+v", user, expected)
+	}
+}
+
+func TestUsers_CreateOrGet(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		v := new(User)
+		json.NewDecoder(r.Body).Decode(v)
+		switch v.ID {
+		case "new":
+			fmt.Fprint(w, `{"id":"new"}`)
+		case "conflict":
+			w.WriteHeader(http.StatusConflict)
+		case "envelope":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"code":400,"message":"employee exists","errors":[{"reason":"alreadyExists"}]}}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	for _, id := range []string{"conflict", "envelope"} {
+		id := id
+		mux.HandleFunc("/employee/"+id, func(w http.ResponseWriter, r *http.Request) {
+			testMethod(t, r, "GET")
+			fmt.Fprintf(w, `{"id":%q,"status":"A"}`, id)
+		})
+	}
+
+	user, created, _, err := client.Users.CreateOrGet(ctx, &User{ID: "new"})
+	if err != nil || !created || user.ID != "new" {
+		t.Errorf("CreateOrGet() = %+v, %v, %v, expected new to be created", user, created, err)
+	}
+
+	for _, id := range []string{"conflict", "envelope"} {
+		user, created, _, err := client.Users.CreateOrGet(ctx, &User{ID: id})
+		if err != nil {
+			t.Fatalf("CreateOrGet(%v) returned error: %v", id, err)
+		}
+		if expected := (&User{ID: id, Status: "A"}); created || !reflect.DeepEqual(user, expected) {
+			t.Errorf("CreateOrGet(%v) = %+v, %v, expected the existing %+v", id, user, created, expected)
+		}
+	}
+
+	if _, _, _, err := client.Users.CreateOrGet(ctx, &User{ID: "invalid"}); err == nil {
+		t.Errorf("CreateOrGet() expected error for a rejected create")
+	}
+}
+
+func TestUser_IsActive(t *testing.T) {
+	tests := []struct {
+		status   string
+		expected bool
+	}{
+		{StatusActive, true},
+		{StatusInactive, false},
+		{"", false},
+		{"X", false},
+		{"a", false},
+	}
+
+	for _, tt := range tests {
+		u := &User{Status: tt.status}
+		if got := u.IsActive(); got != tt.expected {
+			t.Errorf("IsActive() with status %q = %v, expected %v", tt.status, got, tt.expected)
+		}
+	}
+}
+
+func TestUsers_ListChangedSince(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if got, want := r.URL.Query().Get("modifiedSince"), "2017-06-01T15:04:05Z"; got != want {
+			t.Errorf("ListChangedSince() sent modifiedSince=%q, expected %q", got, want)
+		}
+
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			fmt.Fprint(w, `{"employees":[{"id":"a"},{"id":"b"}],"nextCursor":"page2"}`)
+		case "page2":
+			fmt.Fprint(w, `{"employees":[{"id":"c"}]}`)
+		}
+	})
+
+	// The time is sent in UTC whatever its location.
+	since := time.Date(2017, time.June, 1, 8, 4, 5, 0, time.FixedZone("PDT", -7*60*60))
+
+	var got []*User
+	opt := &UsersListOptions{}
+	for {
+		users, resp, err := client.Users.ListChangedSince(ctx, since, opt)
+		if err != nil {
+			t.Fatalf("ListChangedSince() returned error: %v", err)
+		}
+		got = append(got, users...)
+		if resp.NextCursor == "" {
+			break
+		}
+		opt.Cursor = &resp.NextCursor
+	}
+
+	expected := []*User{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ListChangedSince() returned %+v, expected %+v", got, expected)
+	}
+}
+
+func TestUsers_List_shapes(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("page") == "" {
+			fmt.Fprint(w, `{"employees":[{"id":"a"},{"id":"b"}],"total":12,"nextCursor":"next"}`)
+			return
+		}
+		fmt.Fprint(w, ` [{"id":"a"},{"id":"b"}]`)
+	})
+
+	expected := []*User{{ID: "a"}, {ID: "b"}}
+
+	users, resp, err := client.Users.List(ctx, nil)
+	if err != nil {
+		t.Fatalf("List() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(users, expected) {
+		t.Errorf("List() returned %+v, expected %+v", users, expected)
+	}
+	if resp.Total == nil || *resp.Total != 12 || resp.NextCursor != "next" {
+		t.Errorf("List() Total = %v, NextCursor = %q, expected 12 and next", resp.Total, resp.NextCursor)
+	}
+
+	users, resp, err = client.Users.List(ctx, &UsersListOptions{Page: 2})
+	if err != nil {
+		t.Fatalf("List() of a bare array returned error: %v", err)
+	}
+	if !reflect.DeepEqual(users, expected) {
+		t.Errorf("List() of a bare array returned %+v, expected %+v", users, expected)
+	}
+	if resp.Total != nil || resp.NextCursor != "" {
+		t.Errorf("List() of a bare array Total = %v, NextCursor = %q, expected none", resp.Total, resp.NextCursor)
+	}
+}
+
+func TestUsers_BulkGetOrdered(t *testing.T) {
+	setup()
+	defer teardown()
+
+	for _, id := range []string{"a", "b"} {
+		id := id
+		mux.HandleFunc("/employee/"+id, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"id":%q}`, id)
+		})
+	}
+
+	ids := []string{"b", "missing", "a", "b"}
+	users, errs, _, err := client.Users.BulkGetOrdered(ctx, ids, nil)
+	if err != nil {
+		t.Fatalf("BulkGetOrdered() returned error: %v", err)
+	}
+
+	expected := []*User{{ID: "b"}, nil, {ID: "a"}, {ID: "b"}}
+	if !reflect.DeepEqual(users, expected) {
+		t.Errorf("BulkGetOrdered() returned %+v, expected %+v", users, expected)
+	}
+	if len(errs) != len(ids) {
+		t.Fatalf("BulkGetOrdered() returned %d errors, expected %d", len(errs), len(ids))
+	}
+	for i, err := range errs {
+		if (err != nil) != (i == 1) {
+			t.Errorf("BulkGetOrdered() error %d for %v = %v", i, ids[i], err)
+		}
+	}
+}
+
+func TestUsers_pathPrefix(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/hr/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"erick"}`)
+	})
+	mux.HandleFunc("/hr/employee", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"employees":[{"id":"erick"}]}`)
+	})
+
+	SetUsersPathPrefix("/hr/")(client)
+
+	if user, _, err := client.Users.Get(ctx, "erick", nil); err != nil || user.ID != "erick" {
+		t.Errorf("Users.Get() = %+v, %v, expected erick from the prefixed path", user, err)
+	}
+
+	users, resp, err := client.Users.List(ctx, nil)
+	if err != nil || len(users) != 1 {
+		t.Errorf("Users.List() = %+v, %v, expected erick from the prefixed path", users, err)
+	}
+	if got, want := resp.Request.URL.Path, "/hr/employee"; got != want {
+		t.Errorf("Users.List() requested %v, expected %v", got, want)
+	}
+}
+
+func TestUsers_ListInactive(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"status": "I", "page": "2"})
+		fmt.Fprint(w, `{"employees":[{"id":"a","status":"I"},{"id":"b","status":"A"},{"id":"c","status":"I"}]}`)
+	})
+
+	opt := &UsersListOptions{Page: 2, Status: StatusActive}
+	users, _, err := client.Users.ListInactive(ctx, opt)
+	if err != nil {
+		t.Fatalf("ListInactive() returned error: %v", err)
+	}
+
+	expected := []*User{{ID: "a", Status: "I"}, {ID: "c", Status: "I"}}
+	if !reflect.DeepEqual(users, expected) {
+		t.Errorf("ListInactive() returned %+v, expected %+v", users, expected)
+	}
+	if opt.Status != StatusActive {
+		t.Errorf("ListInactive() modified the options, Status = %q", opt.Status)
+	}
+}
+
+func TestUsers_Get_emptyResource(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/ghost", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+
+	user, _, err := client.Users.Get(ctx, "ghost", nil)
+	if err != nil || !reflect.DeepEqual(user, &User{}) {
+		t.Errorf("Users.Get() = %+v, %v, expected an empty User by default", user, err)
+	}
+
+	SetRejectEmptyResources(true)(client)
+
+	if _, _, err := client.Users.Get(ctx, "ghost", nil); err != ErrEmptyResource {
+		t.Errorf("Users.Get() error = %v, expected %v", err, ErrEmptyResource)
+	}
+
+	req, _ := client.NewRequest("GET", "employee/ghost", nil)
+	if _, err := client.Do(ctx, req, new(User)); err != ErrEmptyResource {
+		t.Errorf("Do() error = %v, expected %v", err, ErrEmptyResource)
+	}
+}
+
+func TestUsers_emptyResource_collections(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+
+	SetRejectEmptyResources(true)(client)
+
+	// An empty list envelope means no results, not an empty resource.
+	if users, _, err := client.Users.List(ctx, nil); err != nil || len(users) != 0 {
+		t.Errorf("Users.List() = %v, %v, expected no users", users, err)
+	}
+	if _, _, err := client.Users.Count(ctx, nil); err != ErrCountUnavailable {
+		t.Errorf("Users.Count() error = %v, expected %v", err, ErrCountUnavailable)
+	}
+	if _, _, err := client.Users.GetByEmail(ctx, "ghost@example.com", nil); err != ErrNotFound {
+		t.Errorf("Users.GetByEmail() error = %v, expected %v", err, ErrNotFound)
+	}
+
+	// The user returned by Create is a single resource.
+	if _, _, err := client.Users.Create(ctx, &User{ID: "ghost"}); err != ErrEmptyResource {
+		t.Errorf("Users.Create() error = %v, expected %v", err, ErrEmptyResource)
+	}
+}
+
+func TestFieldsFromStruct(t *testing.T) {
+	type badge struct {
+		ID       string `json:"id"`
+		FullName string `json:"fullName,omitempty"`
+		Email    string
+		Secret   string `json:"-"`
+		internal string
+	}
+
+	expected := []string{"id", "fullName", "Email"}
+	if got := FieldsFromStruct(badge{}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("FieldsFromStruct() returned %v, expected %v", got, expected)
+	}
+	if got := FieldsFromStruct(&badge{}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("FieldsFromStruct(pointer) returned %v, expected %v", got, expected)
+	}
+	if got := FieldsFromStruct("id"); got != nil {
+		t.Errorf("FieldsFromStruct(string) returned %v, expected nil", got)
+	}
+	if got := FieldsFromStruct(nil); got != nil {
+		t.Errorf("FieldsFromStruct(nil) returned %v, expected nil", got)
+	}
+}
+
+func TestUsers_ListAllProgress(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			fmt.Fprint(w, `{"employees":[{"id":"a"},{"id":"b"}],"total":3,"nextCursor":"c2"}`)
+		case "c2":
+			fmt.Fprint(w, `{"employees":[{"id":"c"}],"total":3}`)
+		}
+	})
+
+	var calls [][2]int
+	users, _, err := client.Users.ListAllProgress(ctx, nil, func(fetched, total int) {
+		calls = append(calls, [2]int{fetched, total})
+	})
+	if err != nil {
+		t.Fatalf("Users.ListAllProgress() returned error: %v", err)
+	}
+	if got := len(users); got != 3 || users[0].ID != "a" || users[2].ID != "c" {
+		t.Errorf("Users.ListAllProgress() returned %+v, expected users a, b and c", users)
+	}
+
+	expected := [][2]int{{2, 3}, {3, 3}}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Users.ListAllProgress() progress calls = %v, expected %v", calls, expected)
+	}
+}
+
+func TestUsers_Get_escapesID(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee/", func(w http.ResponseWriter, r *http.Request) {
+		if got, expected := r.URL.EscapedPath(), "/employee/a%2Fb%3Fc"; got != expected {
+			t.Errorf("Users.Get() requested path %q, expected %q", got, expected)
+		}
+		testFormValues(t, r, values{"fields": "id"})
+		fmt.Fprint(w, `{"id":"a/b?c"}`)
+	})
+
+	fields := "id"
+	user, _, err := client.Users.Get(ctx, "a/b?c", &UsersOptions{Fields: &fields})
+	if err != nil {
+		t.Fatalf("Users.Get() returned error: %v", err)
+	}
+	if user.ID != "a/b?c" {
+		t.Errorf("Users.Get() returned ID %q, expected %q", user.ID, "a/b?c")
+	}
+}
+
+func TestUsers_resourceNames(t *testing.T) {
+	setup()
+	defer teardown()
+
+	if err := SetUsersResourceNames("/employee/", "employees")(client); err != nil {
+		t.Fatalf("SetUsersResourceNames() returned error: %v", err)
+	}
+
+	mux.HandleFunc("/employee/erick", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"id":"erick"}`)
+	})
+	mux.HandleFunc("/employees", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			fmt.Fprint(w, `{"employees":[{"id":"erick"}]}`)
+		case "POST":
+			fmt.Fprint(w, `{"id":"andrew"}`)
+		}
+	})
+
+	if _, _, err := client.Users.Get(ctx, "erick", nil); err != nil {
+		t.Errorf("Users.Get() returned error: %v", err)
+	}
+	if users, _, err := client.Users.List(ctx, nil); err != nil || len(users) != 1 {
+		t.Errorf("Users.List() = %+v, %v, expected one user from the collection", users, err)
+	}
+	if _, _, err := client.Users.Create(ctx, &User{ID: "andrew"}); err != nil {
+		t.Errorf("Users.Create() returned error: %v", err)
+	}
+
+	if err := SetUsersResourceNames("employee", "")(client); err == nil {
+		t.Errorf("SetUsersResourceNames() with an empty collection expected error")
+	}
+}
+
+func TestUsers_ListChan(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			fmt.Fprint(w, `{"employees":[{"id":"a"},{"id":"b"}],"nextCursor":"c2"}`)
+		case "c2":
+			fmt.Fprint(w, `{"employees":[{"id":"c"}]}`)
+		}
+	})
+
+	users, errc := client.Users.ListChan(ctx, nil)
+
+	var ids []string
+	for user := range users {
+		ids = append(ids, user.ID)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("Users.ListChan() sent error: %v", err)
+	}
+	if expected := []string{"a", "b", "c"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Users.ListChan() sent %v, expected %v", ids, expected)
+	}
+}
+
+func TestUsers_ListChan_cancel(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/employee", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"employees":[{"id":"a"},{"id":"b"}],"nextCursor":"more"}`)
+	})
+
+	cctx, cancel := context.WithCancel(ctx)
+	users, errc := client.Users.ListChan(cctx, nil)
+
+	if user := <-users; user == nil || user.ID != "a" {
+		t.Fatalf("Users.ListChan() sent %+v first, expected a", user)
+	}
+	cancel()
+
+	for range users {
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("Users.ListChan() error = %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestDiffUsers(t *testing.T) {
+	old := &User{ID: "erick", FullName: "Erick Guevara", Status: StatusActive, Email: "erick@example.com"}
+	updated := old.Merge(&User{FullName: "Erick A. Guevara", Status: StatusInactive})
+
+	expected := map[string]interface{}{"fullName": "Erick A. Guevara", "status": StatusInactive}
+	if got := DiffUsers(old, updated); !reflect.DeepEqual(got, expected) {
+		t.Errorf("DiffUsers() returned %v, expected %v", got, expected)
+	}
+
+	if got := DiffUsers(old, old); got == nil || len(got) != 0 {
+		t.Errorf("DiffUsers() of equal users returned %v, expected an empty map", got)
+	}
+	if got, expected := DiffUsers(nil, &User{ID: "andrew"}), map[string]interface{}{"id": "andrew"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("DiffUsers(nil, user) returned %v, expected %v", got, expected)
+	}
+	if got := DiffUsers(old, nil); got != nil {
+		t.Errorf("DiffUsers(user, nil) returned %v, expected nil", got)
+	}
 }